@@ -16,9 +16,34 @@ import (
 
 var (
 	dbMap      = map[string]*dbStore{}
+	dbInfoMap  = map[*sql.DB]dbInfo{}
 	dbMapGuard sync.RWMutex
+	//无法反查所属数据库的 sql.Conn, sql.Tx 等用这个阈值记录慢查询, 默认是第一个配置了 SlowQueryThreshold 的数据库的
+	defaultSlowQueryThreshold time.Duration
 )
 
+// 慢查询日志里的参数默认隐藏字符串内容, 只保留长度
+var redact = func(args []interface{}) []interface{} {
+	list := make([]interface{}, len(args))
+	for i, arg := range args {
+		switch val := arg.(type) {
+		case string:
+			list[i] = "***(" + strconv.Itoa(len(val)) + ")"
+		case []byte:
+			list[i] = "***(" + strconv.Itoa(len(val)) + ")"
+		default:
+			list[i] = arg
+		}
+	}
+	return list
+}
+
+func SetRedact(rd func(args []interface{}) []interface{}) {
+	if rd != nil {
+		redact = rd
+	}
+}
+
 var pick = func(dbKey string, dbType string, dbs []*sql.DB) (*sql.DB, error) {
 	if dbType != "master" && dbType != "slave" {
 		return nil, errors.New("dbType error")
@@ -49,6 +74,13 @@ var pick = func(dbKey string, dbType string, dbs []*sql.DB) (*sql.DB, error) {
 	}
 }
 
+// SetSlowQueryThreshold 设置 sql.Conn, sql.Tx 等无法反查所属数据库时的慢查询阈值, <=0 不记录
+func SetSlowQueryThreshold(threshold time.Duration) {
+	dbMapGuard.Lock()
+	defaultSlowQueryThreshold = threshold
+	dbMapGuard.Unlock()
+}
+
 func SetPick(pk func(dbKey string, dbType string, dbs []*sql.DB) (*sql.DB, error)) {
 	if pk != nil {
 		pick = pk
@@ -146,6 +178,16 @@ func BaseOpen(dbType string, options Options, dbKey ...string) {
 		dbMap[dbKey_] = &dbStore{}
 	}
 	dbSt := dbMap[dbKey_]
+	if defaultSlowQueryThreshold <= 0 && options.SlowQueryThreshold > 0 {
+		defaultSlowQueryThreshold = options.SlowQueryThreshold
+	}
+	for _, db := range dbs {
+		dbInfoMap[db] = dbInfo{
+			dbKey:              dbKey_,
+			dbType:             dbType,
+			slowQueryThreshold: options.SlowQueryThreshold,
+		}
+	}
 	if dbType == "master" {
 		dbSt.masters = dbs
 		monster.CommonLog.Info("数据库(" + dbKey_ + "): 主库启动成功")
@@ -193,6 +235,7 @@ func BaseClose(dbType string, dbKey ...string) {
 		}
 		for _, db := range dbs {
 			db.Close()
+			delete(dbInfoMap, db)
 		}
 		if dbType == "master" {
 			dbSt.masters = nil
@@ -298,11 +341,11 @@ func Exec(handler Handler, query string, args ...interface{}) (sql.Result, error
 	return ExecContext(context.Background(), handler, query, args...)
 }
 
-func Prepare(handler Handler, query string) (*sql.Stmt, error) {
+func Prepare(handler Handler, query string) (*Stmt, error) {
 	return PrepareContext(context.Background(), handler, query)
 }
 
-func QueryContext(ctx context.Context, handler Handler, col interface{}, query string, args ...interface{}) error {
+func QueryContext(ctx context.Context, handler Handler, col interface{}, query string, args ...interface{}) (err error) {
 	if handler == nil {
 		return errors.New("handler is nil")
 	}
@@ -310,7 +353,8 @@ func QueryContext(ctx context.Context, handler Handler, col interface{}, query s
 	if reflectErr != nil {
 		return reflectErr
 	}
-	monster.CommonLog.Trace("sql("+fmt.Sprintf("%p", handler)+"):", query)
+	var rowNum int64
+	defer logSql(handler, query, args, time.Now(), &rowNum, &err)
 	rows, err := handler.QueryContext(ctx, query, args...)
 	if err != nil {
 		return err
@@ -325,11 +369,12 @@ func QueryContext(ctx context.Context, handler Handler, col interface{}, query s
 		if appendErr != nil {
 			return appendErr
 		}
+		rowNum++
 	}
 	return nil
 }
 
-func QueryRowContext(ctx context.Context, handler Handler, col interface{}, query string, args ...interface{}) error {
+func QueryRowContext(ctx context.Context, handler Handler, col interface{}, query string, args ...interface{}) (err error) {
 	if handler == nil {
 		return errors.New("handler is nil")
 	}
@@ -337,7 +382,8 @@ func QueryRowContext(ctx context.Context, handler Handler, col interface{}, quer
 	if reflectErr != nil {
 		return reflectErr
 	}
-	monster.CommonLog.Trace("sql("+fmt.Sprintf("%p", handler)+"):", query)
+	var rowNum int64
+	defer logSql(handler, query, args, time.Now(), &rowNum, &err)
 	rows, err := handler.QueryContext(ctx, query, args...)
 	if err != nil {
 		return err
@@ -352,26 +398,39 @@ func QueryRowContext(ctx context.Context, handler Handler, col interface{}, quer
 		if appendErr != nil {
 			return appendErr
 		}
+		rowNum++
 	} else {
 		return errors.New("not exists")
 	}
 	return nil
 }
 
-func ExecContext(ctx context.Context, handler Handler, query string, args ...interface{}) (sql.Result, error) {
+func ExecContext(ctx context.Context, handler Handler, query string, args ...interface{}) (result sql.Result, err error) {
 	if handler == nil {
 		return nil, errors.New("handler is nil")
 	}
-	monster.CommonLog.Trace("sql("+fmt.Sprintf("%p", handler)+"):", query)
-	return handler.ExecContext(ctx, query, args...)
+	var rowNum int64
+	defer logSql(handler, query, args, time.Now(), &rowNum, &err)
+	result, err = handler.ExecContext(ctx, query, args...)
+	if err == nil {
+		rowNum, _ = result.RowsAffected()
+	}
+	return result, err
 }
 
-func PrepareContext(ctx context.Context, handler Handler, query string) (*sql.Stmt, error) {
-	monster.CommonLog.Trace("sql("+fmt.Sprintf("%p", handler)+"):", query)
-	return handler.PrepareContext(ctx, query)
+// PrepareContext 返回的 Stmt 记录了所属数据库和 sql, 用 StmtQueryContext 等执行时慢查询日志才有 dbKey 和 sql
+func PrepareContext(ctx context.Context, handler Handler, query string) (stmt *Stmt, err error) {
+	var rowNum int64
+	defer logSql(handler, query, nil, time.Now(), &rowNum, &err)
+	sqlStmt, err := handler.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	_, info := handlerInfo(handler)
+	return &Stmt{Stmt: sqlStmt, info: info, query: query}, nil
 }
 
-func StmtQueryContext(ctx context.Context, stmt *sql.Stmt, col interface{}, args ...interface{}) error {
+func StmtQueryContext(ctx context.Context, stmt *Stmt, col interface{}, args ...interface{}) (err error) {
 	if stmt == nil {
 		return errors.New("stmt is nil")
 	}
@@ -379,6 +438,8 @@ func StmtQueryContext(ctx context.Context, stmt *sql.Stmt, col interface{}, args
 	if reflectErr != nil {
 		return reflectErr
 	}
+	var rowNum int64
+	defer logSql(stmt, stmt.query, args, time.Now(), &rowNum, &err)
 	rows, err := stmt.QueryContext(ctx, args...)
 	if err != nil {
 		return err
@@ -393,11 +454,12 @@ func StmtQueryContext(ctx context.Context, stmt *sql.Stmt, col interface{}, args
 		if appendErr != nil {
 			return appendErr
 		}
+		rowNum++
 	}
 	return nil
 }
 
-func StmtQueryRowContext(ctx context.Context, stmt *sql.Stmt, col interface{}, args ...interface{}) error {
+func StmtQueryRowContext(ctx context.Context, stmt *Stmt, col interface{}, args ...interface{}) (err error) {
 	if stmt == nil {
 		return errors.New("stmt is nil")
 	}
@@ -405,6 +467,8 @@ func StmtQueryRowContext(ctx context.Context, stmt *sql.Stmt, col interface{}, a
 	if reflectErr != nil {
 		return reflectErr
 	}
+	var rowNum int64
+	defer logSql(stmt, stmt.query, args, time.Now(), &rowNum, &err)
 	rows, err := stmt.QueryContext(ctx, args...)
 	if err != nil {
 		return err
@@ -419,14 +483,108 @@ func StmtQueryRowContext(ctx context.Context, stmt *sql.Stmt, col interface{}, a
 		if appendErr != nil {
 			return appendErr
 		}
+		rowNum++
 	} else {
 		return errors.New("not exists")
 	}
 	return nil
 }
 
-func StmtExecContext(ctx context.Context, stmt *sql.Stmt, args ...interface{}) (sql.Result, error) {
-	return stmt.ExecContext(ctx, args...)
+func StmtExecContext(ctx context.Context, stmt *Stmt, args ...interface{}) (result sql.Result, err error) {
+	if stmt == nil {
+		return nil, errors.New("stmt is nil")
+	}
+	var rowNum int64
+	defer logSql(stmt, stmt.query, args, time.Now(), &rowNum, &err)
+	result, err = stmt.ExecContext(ctx, args...)
+	if err == nil {
+		rowNum, _ = result.RowsAffected()
+	}
+	return result, err
+}
+
+// 每条sql都计时, 超过 Options.SlowQueryThreshold 按 Warn 记录慢查询, 否则按 Trace 记录
+// sql.Conn 和 sql.Tx 无法反查所属数据库, 按 SetSlowQueryThreshold 的阈值记录, 通过 ConnContext 和 BeginTx 获取才能带上 dbKey 和主从
+func logSql(handler interface{}, query string, args []interface{}, start time.Time, rowNum *int64, err *error) {
+	duration := time.Since(start)
+	kind, info := handlerInfo(handler)
+	slow := info.slowQueryThreshold > 0 && duration >= info.slowQueryThreshold
+	if !slow && monster.CommonLog.GetLevel() != "trace" {
+		return
+	}
+	fields := map[string]interface{}{
+		"dbKey":    info.dbKey,
+		"role":     info.dbType,
+		"kind":     kind,
+		"duration": duration.String(),
+		"rows":     *rowNum,
+	}
+	if *err != nil {
+		fields["error"] = (*err).Error()
+	}
+	logger := monster.CommonLog.WithFields(fields)
+	if slow {
+		logger.WithField("args", redact(args)).Warn("慢查询("+fmt.Sprintf("%p", handler)+"):", query)
+	} else {
+		logger.Trace("sql("+fmt.Sprintf("%p", handler)+"):", query)
+	}
+}
+
+func handlerInfo(handler interface{}) (string, dbInfo) {
+	switch h := handler.(type) {
+	case *sql.DB:
+		//防止并发写map异常
+		dbMapGuard.RLock()
+		info := dbInfoMap[h]
+		dbMapGuard.RUnlock()
+		return "DB", info
+	case *Conn:
+		return "Conn", h.info
+	case *Tx:
+		return "Tx", h.info
+	case *Stmt:
+		return "Stmt", h.info
+	}
+	//防止并发写map异常
+	dbMapGuard.RLock()
+	info := dbInfo{slowQueryThreshold: defaultSlowQueryThreshold}
+	dbMapGuard.RUnlock()
+	switch handler.(type) {
+	case *sql.Conn:
+		return "Conn", info
+	case *sql.Tx:
+		return "Tx", info
+	default:
+		return fmt.Sprintf("%T", handler), info
+	}
+}
+
+// ConnContext 从主库取出一个连接, 用完需要 Close 放回连接池
+func ConnContext(ctx context.Context, dbKey ...string) (*Conn, error) {
+	db := Master(dbKey...)
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	_, info := handlerInfo(db)
+	return &Conn{Conn: conn, info: info}, nil
+}
+
+// BeginTx 在主库开启事务
+func BeginTx(ctx context.Context, opts *sql.TxOptions, dbKey ...string) (*Tx, error) {
+	db := Master(dbKey...)
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	_, info := handlerInfo(db)
+	return &Tx{Tx: tx, info: info}, nil
 }
 
 func colReflect(col interface{}) (colValueElem reflect.Value, colItemType reflect.Type, colItemTagMap map[string]string, err error) {
@@ -13,6 +13,7 @@ type Options struct {
 	MaxIdleConns          int           //最大保存多少空闲连接
 	StatisticsLog         bool          //是否记录统计日志
 	StatisticsLogDuration time.Duration //多长时间记录一次统计日志, 默认5秒钟记录一次
+	SlowQueryThreshold    time.Duration //sql执行超过该时间按 Warn 记录慢查询, <=0 不记录
 }
 
 type dbStore struct {
@@ -20,6 +21,31 @@ type dbStore struct {
 	slaves  []*sql.DB
 }
 
+type dbInfo struct {
+	dbKey              string
+	dbType             string
+	slowQueryThreshold time.Duration
+}
+
+// Stmt 由 PrepareContext 返回, 记录了所属数据库和 sql, 用于慢查询日志
+type Stmt struct {
+	*sql.Stmt
+	info  dbInfo
+	query string
+}
+
+// Conn 满足 Handler, 记录了所属数据库, 用于慢查询日志
+type Conn struct {
+	*sql.Conn
+	info dbInfo
+}
+
+// Tx 满足 Handler, 记录了所属数据库, 用于慢查询日志
+type Tx struct {
+	*sql.Tx
+	info dbInfo
+}
+
 type Statistics struct {
 	Use  int //正在使用
 	Idle int //正在空闲
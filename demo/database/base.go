@@ -56,6 +56,7 @@ func main() {
 		MaxOpenConns:    10,              //连接池,最大打开10连接,超过阻塞等待
 		MaxIdleConns:    5,               //连接池,最大保存5个空闲连接
 		//StatisticsLog:   true,            //开启统计日志, 记录连接池的基本状态, 文件: log/statistics.log
+		//SlowQueryThreshold: time.Millisecond * 200, //超过200毫秒的sql按 Warn 记录慢查询(耗时,行数,参数), 文件: log/common.log
 	})
 
 	//database.Handler 满足 sql.DB 和 sql.Conn 和 sql.Tx, 所以下面的 handler都可以
@@ -66,8 +67,9 @@ func main() {
 
 	//sql.Conn版本
 	//sql.Conn 和 sql.DB区别在于 sql.Conn自己释放回连接池, 用在http服务里和 http.Request 上下文绑定就比较合理
+	//database.ConnContext 取出的连接会记录所属数据库, 慢查询日志才能带上 dbKey 和主从
 	/*ctx := context.Background()
-	conn, err := database.DB().Conn(ctx)
+	conn, err := database.ConnContext(ctx)
 	if err != nil {
 		panic(err)
 	}
@@ -75,8 +77,7 @@ func main() {
 	conn.Close()*/
 
 	//sql.Tx版本(事务)
-	/*db := database.DB()
-	tx, err := db.Begin()
+	/*tx, err := database.BeginTx(context.Background(), nil)
 	if err != nil {
 		panic(err)
 	}
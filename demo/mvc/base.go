@@ -1,13 +1,17 @@
 package main
 
 import (
-	"errors"
 	"fmt"
 	"github.com/luoshanzhi/monster-go"
 	"github.com/luoshanzhi/monster-go/demo/mvc/object"
 	"github.com/luoshanzhi/monster-go/mvc"
+	"html/template"
 	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
 	"regexp"
+	"time"
 )
 
 var factoryMap = map[string]interface{}{
@@ -24,7 +28,7 @@ func handler(req *http.Request) (mvc.Route, error) {
 	pathReg := regexp.MustCompile(`^/(\w+)/(\w+)`)
 	pathRes := pathReg.FindStringSubmatch(path)
 	if len(pathRes) != 3 {
-		return route, errors.New("错误的路由")
+		return route, mvc.ErrNotFound
 	}
 	// 通过url解析，到相关的控制器模块，控制器模块要先在工厂注册
 	route.ControllerName = monster.FirstUpper(pathRes[1])
@@ -33,6 +37,53 @@ func handler(req *http.Request) (mvc.Route, error) {
 	return route, nil
 }
 
+// 声明式路由, 和 handler 二选一
+// http://127.0.0.1:9020/bird/fly
+// http://127.0.0.1:9020/api/dogs/run?str=a&num=1
+func router() *mvc.Router {
+	r := mvc.NewRouter()
+	r.Get("/bird/fly", "Bird", "Fly").Name("bird.fly")
+	r.Get("/bird/page", "Bird", "Page")
+	r.Post("/bird/upload", "Bird", "Upload")
+	//上传接口单独设置大小限制, 超过输出 413
+	r.Post("/bird/uploads", "Bird", "Uploads").Limits(mvc.Limits{MaxBodySize: 20 << 20, MaxFiles: 9, MaxFileSize: 2 << 20})
+	r.Group("/api", func(api *mvc.Router) {
+		api.Get("/dogs/run", "Dog", "Run")
+		api.Get("/dogs/{id:[0-9]+}/run", "Dog", "Run")
+		api.Get("/dogs/{id:[0-9]+}", "Dog", "Find")
+		api.Get("/dogs/{id:[0-9]+}/info", "Dog", "Info")
+		api.Post("/dogs/json", "Dog", "Json")
+	})
+	//断点续传(tus 协议): POST /files 创建, PATCH /files/{id} 分段上传, HEAD /files/{id} 查询进度
+	r.Mount("/files", resumable()).Limits(mvc.Limits{MaxBodySize: 64 << 20})
+	//按约定自动注册: /auto/bird/fly, /auto/dog/run ...
+	r.AutoRoutes("/auto", "Bird", "Dog")
+	return r
+}
+
+// 断点续传, 分片临时保存在 ./demo/mvc/upload/tmp, 完成后移到 ./demo/mvc/upload
+func resumable() *mvc.ResumableUpload {
+	upload := mvc.NewResumableUpload("./demo/mvc/upload/tmp", "/files")
+	upload.MaxSize = 1 << 30 //1GB
+	upload.OnComplete = func(req *http.Request, file *mvc.ResumableFile) error {
+		name := mvc.RandomFileName(file.Metadata["filename"])
+		return os.Rename(file.Path, filepath.Join("./demo/mvc/upload", name))
+	}
+	return upload
+}
+
+// 模板引擎, release 环境只解析一次, 其他环境每次请求重新解析
+func templates() *mvc.Templates {
+	tpl := mvc.NewTemplates("./demo/mvc/templates")
+	tpl.DefaultLayout = "layouts/base"
+	tpl.Funcs = template.FuncMap{
+		"date": func(t time.Time) string {
+			return t.Format("2006-01-02 15:04:05")
+		},
+	}
+	return tpl
+}
+
 func prepare(server *mvc.Server, httpServer *http.Server) {
 	//这里可以更改 http.Server 实例信息
 	fmt.Println("prepare(" + server.Addr + ")")
@@ -46,12 +97,15 @@ func prepare(server *mvc.Server, httpServer *http.Server) {
 // http://127.0.0.1:9021/bigBird/fly 只有9021端口可以调用
 func main() {
 	monster.Init(factoryMap) //初始化工厂
+	//控制器方法可以直接传 *object.CurrentUser
+	mvc.RegisterResolver(reflect.TypeOf((*object.CurrentUser)(nil)), object.ResolveCurrentUser)
 	//9021端口添加一个拦截器
 	var interceptors9021 = []mvc.Interceptor{
 		monster.Factory("InterceptorName").(mvc.Interceptor),
 	}
 	mvc.Serve(
-		&mvc.Server{Addr: ":9020", Handler: handler, Prepare: prepare},
+		//部署在 nginx 后面时把 nginx 的地址加到 TrustedProxies, Context.ClientIP 才会取 X-Forwarded-For
+		&mvc.Server{Addr: ":9020", Handler: router().Handler, Prepare: prepare, Templates: templates(), TrustedProxies: []string{"127.0.0.1"}},
 		&mvc.Server{Addr: ":9021", Handler: handler, Prepare: prepare, Interceptors: interceptors9021},
 		&mvc.Server{Addr: ":9022", Handler: handler, Prepare: prepare},
 		//CertFile 和 KeyFile 同时不为空就是 https
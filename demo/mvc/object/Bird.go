@@ -1,14 +1,10 @@
 package object
 
 import (
-	"errors"
 	"github.com/luoshanzhi/monster-go"
-	"io"
-	"mime/multipart"
+	"github.com/luoshanzhi/monster-go/mvc"
 	"net/http"
-	"os"
-	"path"
-	"strings"
+	"time"
 )
 
 type Bird struct {
@@ -33,73 +29,61 @@ func (the *Bird) Fly(req *http.Request) *Json { //不指定请求Method，默认
 	return jsonView
 }
 
-// 上传单个文件
-func (the *Bird) Upload(param UploadFile) *Json {
-	jsonView := monster.Factory("Json").(*Json)
-	file := param.File
-	if file == nil {
-		jsonView.Msg = "上传文件不能为空"
-		return jsonView
-	}
-	err := the.saveUploadFile(file)
+// 上传文件保存到本地目录, 也可以实现 mvc.Storage 保存到对象存储
+var uploadStorage = mvc.NewLocalStorage("./demo/mvc/upload")
+
+// 按文件内容判断类型, 用随机文件名保存
+var uploadOptions = mvc.UploadOptions{
+	MaxSize:    2 << 20, //2MB
+	AllowTypes: []string{"image/jpeg", "image/png", "image/gif"},
+	RandomName: true,
+}
+
+// 上传单个文件, 返回的 error 由框架输出对应的状态码
+func (the *Bird) Upload(req *http.Request, param UploadFile) (*Json, error) {
+	//param 已经按 validate 标签校验过
+	file, err := mvc.SaveUpload(req.Context(), uploadStorage, param.File, uploadOptions)
 	if err != nil {
-		jsonView.Msg = "上传文件异常"
-		return jsonView
+		return nil, err
 	}
+	jsonView := monster.Factory("Json").(*Json)
+	jsonView.Data = file
 	jsonView.Code = 0
 	jsonView.Msg = "成功"
-	return jsonView
+	return jsonView, nil
 }
 
 // 上传多个文件
-func (the *Bird) Uploads(param UploadFiles) *Json {
-	jsonView := monster.Factory("Json").(*Json)
-	files := param.Files
-	if len(files) == 0 {
-		jsonView.Msg = "上传文件不能为空"
-		return jsonView
-	}
-	for _, file := range files {
-		err := the.saveUploadFile(file)
+func (the *Bird) Uploads(req *http.Request, param UploadFiles) (*Json, error) {
+	var files []*mvc.UploadFile
+	for _, fileHeader := range param.Files {
+		file, err := mvc.SaveUpload(req.Context(), uploadStorage, fileHeader, uploadOptions)
 		if err != nil {
-			jsonView.Msg = "上传文件异常"
-			return jsonView
+			return nil, err
 		}
+		files = append(files, file)
 	}
+	jsonView := monster.Factory("Json").(*Json)
+	jsonView.Data = files
 	jsonView.Code = 0
 	jsonView.Msg = "成功"
-	return jsonView
+	return jsonView, nil
 }
 
-func (the *Bird) saveUploadFile(fileHeader *multipart.FileHeader) error {
-	fileType := strings.ToLower(strings.TrimSpace(fileHeader.Header.Get("Content-Type")))
-	name := strings.TrimSpace(fileHeader.Filename)
-	size := int(fileHeader.Size)
-	maxSize := 2 * 1024 * 1024 //2MB
-	if name == "" || size <= 0 || size > maxSize || (fileType != "image/pjpeg" && fileType != "image/jpeg" && fileType != "image/png" && fileType != "image/x-png" && fileType != "image/gif") {
-		return errors.New("错误的文件")
-	}
-	fileName := path.Base(name)
-	destDir := "./demo/mvc/upload/"
-	if _, err := os.Stat(destDir); os.IsNotExist(err) {
-		if err := os.Mkdir(destDir, os.ModePerm); err != nil {
-			return err
-		}
-	}
-	file, fileErr := fileHeader.Open()
-	if fileErr != nil {
-		return fileErr
+// 也可以直接返回结构、map、切片、string、[]byte、io.Reader, 框架按 Accept 头(默认 json)自动序列化
+// http://127.0.0.1:9022/bird/sing
+func (the *Bird) Sing(req *http.Request) map[string]interface{} {
+	return map[string]interface{}{
+		"song": "singing(" + req.Host + ")",
+		"time": the.common.Now(),
 	}
-	defer file.Close()
-	newFile, newFileErr := os.Create(destDir + fileName)
-	if newFileErr != nil {
-		return newFileErr
-	}
-	defer newFile.Close()
-	// 复制文件到目标目录
-	_, copyErr := io.Copy(newFile, file)
-	if copyErr != nil {
-		return copyErr
-	}
-	return nil
+}
+
+// 服务端渲染, 模板在 demo/mvc/templates, 页面 bird/page 套用布局 layouts/base
+// http://127.0.0.1:9020/bird/page
+func (the *Bird) Page(_ mvc.GET) *mvc.HTMLView {
+	return mvc.HTML("bird/page", map[string]interface{}{
+		"Name": "bird",
+		"Time": time.Now(),
+	})
 }
@@ -0,0 +1,21 @@
+package object
+
+import (
+	"github.com/luoshanzhi/monster-go/mvc"
+	"net/http"
+	"reflect"
+)
+
+// CurrentUser 当前登录用户, 用 mvc.RegisterResolver 注册后控制器方法可以直接传 *CurrentUser
+type CurrentUser struct {
+	Name string `json:"name"`
+}
+
+// ResolveCurrentUser 从 header 的 token 取当前用户, 没有登录返回 401, 不再调用控制器
+func ResolveCurrentUser(w http.ResponseWriter, req *http.Request) (reflect.Value, error) {
+	token := req.Header.Get("X-Token")
+	if token == "" {
+		return reflect.Value{}, mvc.Unauthorized("请先登录")
+	}
+	return reflect.ValueOf(&CurrentUser{Name: token}), nil
+}
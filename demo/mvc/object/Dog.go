@@ -1,6 +1,7 @@
 package object
 
 import (
+	"context"
 	"fmt"
 	"github.com/luoshanzhi/monster-go"
 	"github.com/luoshanzhi/monster-go/mvc"
@@ -37,3 +38,48 @@ func (the *Dog) Json(req *http.Request, param Param, _ mvc.POST) *Json {
 	jsonView.Msg = "成功"
 	return jsonView
 }
+
+// 也可以返回 (View, error) 或者只返回 error, error 不为 nil 时交给 Server.ErrorHandler 输出
+// mvc.NotFound, mvc.Forbidden, mvc.BadRequest 等错误会输出对应的状态码
+// url: http://127.0.0.1:9020/api/dogs/1
+func (the *Dog) Find(param Param) (*Json, error) {
+	if param.Id <= 0 {
+		return nil, mvc.NotFound("狗不存在")
+	}
+	jsonView := monster.Factory("Json").(*Json)
+	jsonView.Data = param.Id
+	jsonView.Msg = "成功"
+	return jsonView, nil
+}
+
+// 嵌套参数, 参考url: http://127.0.0.1:9022/dog/order?user[name]=a&user.address.city=b&items[0][id]=1&items[0][qty]=2&items[1].id=3&attrs[color]=red&tags[]=x&tags[]=y
+func (the *Dog) Order(param OrderParam) OrderParam {
+	return param
+}
+
+// context.Context 是请求的 context, *CurrentUser 由 mvc.RegisterResolver 注册的 object.ResolveCurrentUser 取值
+// url: http://127.0.0.1:9022/dog/me  header: X-Token: monster
+func (the *Dog) Me(ctx context.Context, user *CurrentUser) *Json {
+	jsonView := monster.Factory("Json").(*Json)
+	select {
+	case <-ctx.Done():
+		jsonView.Msg = "请求已取消"
+	default:
+		jsonView.Data = user
+		jsonView.Msg = "成功"
+	}
+	return jsonView
+}
+
+// *mvc.Context 包装了 w 和 req, 取路径参数, url 参数, cookie, 客户端 ip 等
+// url: http://127.0.0.1:9020/api/dogs/1/info?lang=zh
+func (the *Dog) Info(ctx *mvc.Context) *mvc.JsonView {
+	ctx.SetCookie(&http.Cookie{Name: "lang", Value: ctx.Query("lang"), MaxAge: 3600})
+	return ctx.JSON(map[string]interface{}{
+		"id":    ctx.ParamInt("id", 0),
+		"lang":  ctx.Query("lang"),
+		"last":  ctx.Cookie("lang"),
+		"ip":    ctx.ClientIP(),
+		"route": ctx.Route().MethodName,
+	})
+}
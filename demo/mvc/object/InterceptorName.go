@@ -24,6 +24,9 @@ func (the *InterceptorName) Init() {
 func (the *InterceptorName) Before(w http.ResponseWriter, req *http.Request, throughout []reflect.Value, throughoutIndex int) mvc.View {
 	//把url里的控制器 bigBird 改成 bird
 	req.URL.Path = regexp.MustCompile(`(?i)bigBird`).ReplaceAllString(req.URL.Path, "bird")
+	//mvc.Body 读取请求 body, 同一个请求只读一次, 不影响后面控制器取参数, 比如在这里校验签名
+	//这里还没有路由, 只按 Server.Limits 限制大小, 上传文件的 body 不读, 返回 mvc.ErrStreamBody
+	//body, err := mvc.Body(req)
 	//返回 nil 代表拦截器成功通过
 	//返回 mvc.View 代表成功被拦截，框架输出返回视图
 	return nil
@@ -1,5 +1,9 @@
 package object
 
+import "time"
+
+// 参数结构加上 func (Param) Strict() {} 或者 mvc.Server.StrictBinding 为 true 就是严格模式:
+// num=abc 这样转换失败, json 格式错误或有多余字段, 数组装不下时输出 400, 不会把零值交给控制器
 type Param struct {
 	//http提交参数时，参数兼容首字母大小写
 	Str     string     //也支持 *string
@@ -9,4 +13,37 @@ type Param struct {
 	NumArr1 *[]int     //也支持 *[]int
 	StrArr2 *[2]string //也支持 *[2]string, 如该参数长度超过数组，只取数组长度的参数
 	NumArr2 [2]int     //也支持 *[2]int, 如该参数长度超过数组，只取数组长度的参数
+	Id      int        `path:"id"` //路径参数, 如路由 /api/dogs/{id}/run
+	//指定来源后只从该来源取值: query(get区), form(post区), header, cookie
+	Page  int    `query:"page" default:"1"` //取不到值时用 default
+	Token string `header:"X-Token"`
+	Sid   string `cookie:"sid"`
+	//没有来源标签时先按 json 名字取值, mvc.Server.ExplicitBinding 为 true 时只按 json 名字取值
+	NickName string `json:"nick_name"`
+	//time.Time 按 layout 解析(默认 RFC3339), 也支持 time.Duration, encoding.TextUnmarshaler, json.Unmarshaler 和 mvc.RegisterConverter 注册的类型
+	Birthday time.Time     `layout:"2006-01-02"`
+	Timeout  time.Duration //如 timeout=1m30s
+}
+
+// 嵌套参数, 复杂表单不用 json 也能提交:
+// user[name]=a&user.address.city=b&items[0][id]=1&items[0][qty]=2&items[1].id=3&attrs[color]=red&tags[]=x&tags[]=y
+type OrderParam struct {
+	User  OrderUser
+	Items []OrderItem
+	Attrs map[string]string
+	Tags  []string
+}
+
+type OrderUser struct {
+	Name    string
+	Address *OrderAddress //也支持结构指针
+}
+
+type OrderAddress struct {
+	City string
+}
+
+type OrderItem struct {
+	Id  int
+	Qty int
 }
@@ -5,5 +5,6 @@ import "mime/multipart"
 type UploadFile struct {
 	//http提交参数时，参数兼容首字母大小写
 	//只能提交 multipart.FileHeader 指针类型
-	File *multipart.FileHeader
+	//validate 校验不通过时不会调用控制器, 直接输出 400 和字段错误
+	File *multipart.FileHeader `validate:"required,maxsize=2MB,mime=image/jpeg|image/png|image/gif"`
 }
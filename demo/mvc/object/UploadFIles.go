@@ -5,5 +5,5 @@ import "mime/multipart"
 type UploadFiles struct {
 	//http提交参数时，参数兼容首字母大小写
 	//只能提交 multipart.FileHeader 指针类型
-	Files []*multipart.FileHeader
+	Files []*multipart.FileHeader `validate:"required,max=9,maxsize=2MB,mime=image/jpeg|image/png|image/gif"`
 }
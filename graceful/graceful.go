@@ -54,7 +54,16 @@ func reload(files []File) error {
 		graceful = append(graceful, strings.TrimSpace(item.Addr))
 		extraFiles = append(extraFiles, item.File)
 	}
-	args := []string{"-graceful", strings.Join(graceful, ",")}
+	//保留原有命令行参数(如 -logMode), 替换旧的 -graceful
+	var args []string
+	for i, length := 1, len(os.Args); i < length; i++ {
+		if strings.TrimSpace(os.Args[i]) == "-graceful" {
+			i++
+			continue
+		}
+		args = append(args, os.Args[i])
+	}
+	args = append(args, "-graceful", strings.Join(graceful, ","))
 	cmd := exec.Command(os.Args[0], args...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -3,3 +3,17 @@ package monster
 type Multiton interface {
 	Multiton()
 }
+
+// Logger 框架内部使用的日志接口, 默认实现是 logrus, 可替换成其他日志库
+type Logger interface {
+	WithField(key string, value interface{}) Logger
+	WithFields(fields map[string]interface{}) Logger
+	Trace(args ...interface{})
+	Debug(args ...interface{})
+	Info(args ...interface{})
+	Warn(args ...interface{})
+	Error(args ...interface{})
+	Fatal(args ...interface{}) //输出后退出程序
+	SetLevel(level string) error
+	GetLevel() string
+}
@@ -0,0 +1,125 @@
+package monster
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	levelCycle      = []string{"trace", "debug", "info", "warn", "error"}
+	levelTimers     = map[Logger]*time.Timer{}
+	levelTimerGuard sync.Mutex
+)
+
+// SetLogLevel 运行时修改日志级别, 配置了 logLevelRevert(秒) 时到期自动恢复成配置的 logLevel
+func SetLogLevel(logger Logger, level string) error {
+	revert := time.Duration(CurEnvConfig.LogLevelRevert) * time.Second
+	//防止并发写map异常, 修改级别也放在锁里, 不会被正在恢复的旧定时器覆盖
+	levelTimerGuard.Lock()
+	defer levelTimerGuard.Unlock()
+	if err := logger.SetLevel(level); err != nil {
+		return err
+	}
+	if timer, ok := levelTimers[logger]; ok {
+		timer.Stop()
+		delete(levelTimers, logger)
+	}
+	if revert > 0 && !sameLevel(level, logLevel) {
+		var timer *time.Timer
+		timer = time.AfterFunc(revert, func() {
+			levelTimerGuard.Lock()
+			//Stop 之前已经触发的旧定时器不能删除新定时器, 也不能覆盖刚设置的级别
+			if levelTimers[logger] != timer {
+				levelTimerGuard.Unlock()
+				return
+			}
+			delete(levelTimers, logger)
+			err := logger.SetLevel(logLevel)
+			levelTimerGuard.Unlock()
+			if err == nil {
+				logger.Info("日志级别已恢复: " + logLevel)
+			}
+		})
+		levelTimers[logger] = timer
+	}
+	return nil
+}
+
+// SetLogLevels 同时修改所有框架日志的级别
+func SetLogLevels(level string) error {
+	for _, logger := range []Logger{StatisticsLog, AccessLog, CommonLog, ErrorLog} {
+		if err := SetLogLevel(logger, level); err != nil {
+			return err
+		}
+	}
+	CommonLog.Info("日志级别已修改: " + level)
+	return nil
+}
+
+// 按 trace,debug,info,warn,error 顺序切换到下一个级别
+func cycleLogLevel() {
+	current := CommonLog.GetLevel()
+	next := levelCycle[0]
+	for i, level := range levelCycle {
+		if sameLevel(level, current) {
+			next = levelCycle[(i+1)%len(levelCycle)]
+			break
+		}
+	}
+	SetLogLevels(next)
+}
+
+// 定时检查 logLevelFile, 文件修改后按内容设置级别, 内容为空或文件删除就恢复成配置的 logLevel
+func watchLogLevelFile(file string) {
+	defer Recover()
+	var modTime time.Time
+	if info, err := os.Stat(file); err == nil {
+		modTime = info.ModTime()
+	}
+	for {
+		time.Sleep(time.Second * 2)
+		info, err := os.Stat(file)
+		if err != nil {
+			if !modTime.IsZero() {
+				modTime = time.Time{}
+				SetLogLevels(logLevel)
+			}
+			continue
+		}
+		if info.ModTime().Equal(modTime) {
+			continue
+		}
+		modTime = info.ModTime()
+		b, readErr := os.ReadFile(file)
+		if readErr != nil {
+			continue
+		}
+		level := strings.TrimSpace(string(b))
+		if level == "" {
+			level = logLevel
+		}
+		if err := SetLogLevels(level); err != nil {
+			ErrorLog.Error("日志级别文件错误:", err)
+		}
+	}
+}
+
+func watchLogLevel() {
+	watchLogLevelSignal()
+	if file := strings.TrimSpace(CurEnvConfig.LogLevelFile); file != "" {
+		go watchLogLevelFile(file)
+	}
+}
+
+func sameLevel(a string, b string) bool {
+	normalize := func(level string) string {
+		level = strings.ToLower(strings.TrimSpace(level))
+		if level == "warning" {
+			level = "warn"
+		}
+		return level
+	}
+	return normalize(a) == normalize(b)
+}
@@ -0,0 +1,200 @@
+package monster
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 同时去重的日志最多这么多条, 超过后新的日志不再去重, 只受每秒上限限制, 防止内容各不相同的日志占满内存
+const maxRepeats = 1000
+
+// SamplingLogger 日志去重和限流: window 内相同日志只输出一次, 到期再输出重复次数; 每秒超过 perSecond 的日志丢弃
+type SamplingLogger struct {
+	logger  Logger
+	fields  map[string]interface{}
+	sampler *sampler
+}
+
+type sampler struct {
+	window    time.Duration
+	perSecond int
+	guard     sync.Mutex
+	second    int64
+	count     int
+	dropped   int
+	repeats   map[string]*repeat
+}
+
+type repeat struct {
+	logger Logger
+	level  string
+	msg    string
+	count  int
+}
+
+func NewSamplingLogger(logger Logger, setting LogSamplingSetting) *SamplingLogger {
+	return &SamplingLogger{
+		logger: logger,
+		sampler: &sampler{
+			window:    time.Duration(setting.Window) * time.Second,
+			perSecond: setting.PerSecond,
+			repeats:   make(map[string]*repeat),
+		},
+	}
+}
+
+func (the *SamplingLogger) WithField(key string, value interface{}) Logger {
+	return the.WithFields(map[string]interface{}{key: value})
+}
+
+func (the *SamplingLogger) WithFields(fields map[string]interface{}) Logger {
+	newFields := make(map[string]interface{}, len(the.fields)+len(fields))
+	for key, value := range the.fields {
+		newFields[key] = value
+	}
+	for key, value := range fields {
+		newFields[key] = value
+	}
+	return &SamplingLogger{
+		logger:  the.logger.WithFields(fields),
+		fields:  newFields,
+		sampler: the.sampler,
+	}
+}
+
+func (the *SamplingLogger) Trace(args ...interface{}) {
+	the.log("trace", args...)
+}
+
+func (the *SamplingLogger) Debug(args ...interface{}) {
+	the.log("debug", args...)
+}
+
+func (the *SamplingLogger) Info(args ...interface{}) {
+	the.log("info", args...)
+}
+
+func (the *SamplingLogger) Warn(args ...interface{}) {
+	the.log("warn", args...)
+}
+
+func (the *SamplingLogger) Error(args ...interface{}) {
+	the.log("error", args...)
+}
+
+// Fatal 不去重也不限流
+func (the *SamplingLogger) Fatal(args ...interface{}) {
+	the.logger.Fatal(args...)
+}
+
+func (the *SamplingLogger) SetLevel(level string) error {
+	return the.logger.SetLevel(level)
+}
+
+func (the *SamplingLogger) GetLevel() string {
+	return the.logger.GetLevel()
+}
+
+func (the *SamplingLogger) log(level string, args ...interface{}) {
+	//级别不够的日志本来就不会输出, 不能占用去重和每秒上限的名额
+	if !levelEnabled(level, the.logger.GetLevel()) {
+		return
+	}
+	sp := the.sampler
+	msg := fmt.Sprint(args...)
+	dropped := 0
+	sp.guard.Lock()
+	if sp.window > 0 {
+		key := level + "|" + msg + "|" + fmt.Sprint(the.fields)
+		if rp, ok := sp.repeats[key]; ok {
+			rp.count++
+			sp.guard.Unlock()
+			return
+		}
+		if len(sp.repeats) < maxRepeats {
+			sp.repeats[key] = &repeat{logger: the.logger, level: level, msg: msg}
+			time.AfterFunc(sp.window, func() {
+				sp.flush(key)
+			})
+		}
+	}
+	if sp.perSecond > 0 {
+		now := time.Now().Unix()
+		if now != sp.second {
+			dropped = sp.dropped
+			sp.second = now
+			sp.count = 0
+			sp.dropped = 0
+		}
+		if sp.count >= sp.perSecond {
+			sp.dropped++
+			sp.guard.Unlock()
+			return
+		}
+		sp.count++
+	}
+	sp.guard.Unlock()
+	if dropped > 0 {
+		the.logger.WithField("dropped", dropped).Warn("日志超出每秒上限(" + strconv.Itoa(sp.perSecond) + "), 已丢弃")
+	}
+	logAt(the.logger, level, msg)
+}
+
+func (the *sampler) flush(key string) {
+	the.guard.Lock()
+	rp, ok := the.repeats[key]
+	delete(the.repeats, key)
+	the.guard.Unlock()
+	if ok && rp.count > 0 {
+		logAt(rp.logger.WithField("repeat", rp.count), rp.level, rp.msg+" (重复"+strconv.Itoa(rp.count)+"次)")
+	}
+}
+
+// 当前级别是 current 时 level 级别的日志会不会输出, 级别写错时当作输出
+func levelEnabled(level string, current string) bool {
+	lv, err := logrus.ParseLevel(level)
+	if err != nil {
+		return true
+	}
+	currentLv, err := logrus.ParseLevel(current)
+	if err != nil {
+		return true
+	}
+	return lv <= currentLv
+}
+
+func logAt(logger Logger, level string, msg string) {
+	switch level {
+	case "trace":
+		logger.Trace(msg)
+	case "debug":
+		logger.Debug(msg)
+	case "info":
+		logger.Info(msg)
+	case "warn":
+		logger.Warn(msg)
+	default:
+		logger.Error(msg)
+	}
+}
+
+// 按 logSampling 配置给框架日志加上去重和限流, key: statistics,access,common,error
+func setLogSampling() {
+	set := func(logger Logger, name string) Logger {
+		setting, ok := CurEnvConfig.LogSampling[name]
+		if !ok || (setting.Window <= 0 && setting.PerSecond <= 0) {
+			return logger
+		}
+		if sl, ok := logger.(*SamplingLogger); ok {
+			logger = sl.logger
+		}
+		return NewSamplingLogger(logger, setting)
+	}
+	StatisticsLog = set(StatisticsLog, "statistics")
+	AccessLog = set(AccessLog, "access")
+	CommonLog = set(CommonLog, "common")
+	ErrorLog = set(ErrorLog, "error")
+}
@@ -0,0 +1,41 @@
+package monster
+
+import (
+	"bytes"
+	"github.com/sirupsen/logrus"
+	"strings"
+	"testing"
+)
+
+func newTestSamplingLogger(setting LogSamplingSetting) (*SamplingLogger, *bytes.Buffer) {
+	buf := &bytes.Buffer{}
+	logger := logrus.New()
+	logger.SetOutput(buf)
+	logger.SetLevel(logrus.InfoLevel)
+	return NewSamplingLogger(&LogrusLogger{entry: logrus.NewEntry(logger)}, setting), buf
+}
+
+// 级别不够的日志不能占用每秒上限, 否则真正的错误会被丢掉
+func TestSamplingLoggerSkipsDisabledLevels(t *testing.T) {
+	logger, buf := newTestSamplingLogger(LogSamplingSetting{Window: 10, PerSecond: 5})
+	for i := 0; i < 10; i++ {
+		logger.Trace("trace")
+	}
+	logger.Error("redis down")
+	if !strings.Contains(buf.String(), "redis down") {
+		t.Fatalf("error log dropped, output: %q", buf.String())
+	}
+	if len(logger.sampler.repeats) != 1 {
+		t.Fatalf("repeats = %d, want 1", len(logger.sampler.repeats))
+	}
+}
+
+func TestSamplingLoggerRepeatsLimit(t *testing.T) {
+	logger, _ := newTestSamplingLogger(LogSamplingSetting{Window: 10})
+	for i := 0; i < maxRepeats*2; i++ {
+		logger.Error("error ", i)
+	}
+	if len(logger.sampler.repeats) > maxRepeats {
+		t.Fatalf("repeats = %d, want <= %d", len(logger.sampler.repeats), maxRepeats)
+	}
+}
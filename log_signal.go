@@ -0,0 +1,21 @@
+//go:build !windows
+
+package monster
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// kill -USR1 pid 循环切换日志级别
+func watchLogLevelSignal() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGUSR1)
+	go func() {
+		defer Recover()
+		for range ch {
+			cycleLogLevel()
+		}
+	}()
+}
@@ -0,0 +1,5 @@
+package monster
+
+// windows 没有 SIGUSR1, 只能用 logLevelFile 修改日志级别
+func watchLogLevelSignal() {
+}
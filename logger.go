@@ -0,0 +1,125 @@
+package monster
+
+import (
+	"github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"strings"
+)
+
+// LogrusLogger 默认日志实现
+type LogrusLogger struct {
+	entry *logrus.Entry
+}
+
+func NewLogrusLogger(logger *logrus.Logger) *LogrusLogger {
+	return &LogrusLogger{entry: logrus.NewEntry(logger)}
+}
+
+// Logrus 取出原始 logrus.Logger, 可以自定义输出和格式
+func (the *LogrusLogger) Logrus() *logrus.Logger {
+	return the.entry.Logger
+}
+
+func (the *LogrusLogger) WithField(key string, value interface{}) Logger {
+	return &LogrusLogger{entry: the.entry.WithField(key, value)}
+}
+
+func (the *LogrusLogger) WithFields(fields map[string]interface{}) Logger {
+	return &LogrusLogger{entry: the.entry.WithFields(fields)}
+}
+
+func (the *LogrusLogger) Trace(args ...interface{}) {
+	the.entry.Trace(args...)
+}
+
+func (the *LogrusLogger) Debug(args ...interface{}) {
+	the.entry.Debug(args...)
+}
+
+func (the *LogrusLogger) Info(args ...interface{}) {
+	the.entry.Info(args...)
+}
+
+func (the *LogrusLogger) Warn(args ...interface{}) {
+	the.entry.Warn(args...)
+}
+
+func (the *LogrusLogger) Error(args ...interface{}) {
+	the.entry.Error(args...)
+}
+
+func (the *LogrusLogger) Fatal(args ...interface{}) {
+	the.entry.Fatal(args...)
+}
+
+func (the *LogrusLogger) SetLevel(level string) error {
+	lv, err := logrus.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	the.entry.Logger.SetLevel(lv)
+	return nil
+}
+
+func (the *LogrusLogger) GetLevel() string {
+	return the.entry.Logger.GetLevel().String()
+}
+
+// NopLogger 什么都不输出, 用于测试
+type NopLogger struct {
+	level string
+}
+
+func NewNopLogger() *NopLogger {
+	return &NopLogger{level: "info"}
+}
+
+func (the *NopLogger) WithField(key string, value interface{}) Logger {
+	return the
+}
+
+func (the *NopLogger) WithFields(fields map[string]interface{}) Logger {
+	return the
+}
+
+func (the *NopLogger) Trace(args ...interface{}) {}
+
+func (the *NopLogger) Debug(args ...interface{}) {}
+
+func (the *NopLogger) Info(args ...interface{}) {}
+
+func (the *NopLogger) Warn(args ...interface{}) {}
+
+func (the *NopLogger) Error(args ...interface{}) {}
+
+func (the *NopLogger) Fatal(args ...interface{}) {
+	os.Exit(1)
+}
+
+func (the *NopLogger) SetLevel(level string) error {
+	lv, err := logrus.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	the.level = lv.String()
+	return nil
+}
+
+func (the *NopLogger) GetLevel() string {
+	return the.level
+}
+
+// LogWriter 把写入内容按行输出成 Error 日志, 用在 http.Server.ErrorLog 等只接受 io.Writer 的地方
+func LogWriter(logger Logger) io.Writer {
+	return &logWriter{logger: logger}
+}
+
+type logWriter struct {
+	logger Logger
+}
+
+func (the *logWriter) Write(p []byte) (int, error) {
+	the.logger.Error(strings.TrimRight(string(p), "\r\n"))
+	return len(p), nil
+}
@@ -0,0 +1,112 @@
+//go:build go1.21
+
+package monster
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	slogLevelTrace = slog.LevelDebug - 4
+	slogLevelFatal = slog.LevelError + 4
+)
+
+// SlogLogger 把日志交给 slog.Handler 输出
+type SlogLogger struct {
+	handler slog.Handler
+	level   *slog.LevelVar
+}
+
+func NewSlogLogger(handler slog.Handler) *SlogLogger {
+	level := new(slog.LevelVar)
+	level.Set(slogLevelTrace)
+	return &SlogLogger{handler: handler, level: level}
+}
+
+func (the *SlogLogger) WithField(key string, value interface{}) Logger {
+	return &SlogLogger{handler: the.handler.WithAttrs([]slog.Attr{slog.Any(key, value)}), level: the.level}
+}
+
+func (the *SlogLogger) WithFields(fields map[string]interface{}) Logger {
+	attrs := make([]slog.Attr, 0, len(fields))
+	for key, value := range fields {
+		attrs = append(attrs, slog.Any(key, value))
+	}
+	return &SlogLogger{handler: the.handler.WithAttrs(attrs), level: the.level}
+}
+
+func (the *SlogLogger) log(level slog.Level, args ...interface{}) {
+	ctx := context.Background()
+	if level < the.level.Level() || !the.handler.Enabled(ctx, level) {
+		return
+	}
+	record := slog.NewRecord(time.Now(), level, fmt.Sprint(args...), 0)
+	the.handler.Handle(ctx, record)
+}
+
+func (the *SlogLogger) Trace(args ...interface{}) {
+	the.log(slogLevelTrace, args...)
+}
+
+func (the *SlogLogger) Debug(args ...interface{}) {
+	the.log(slog.LevelDebug, args...)
+}
+
+func (the *SlogLogger) Info(args ...interface{}) {
+	the.log(slog.LevelInfo, args...)
+}
+
+func (the *SlogLogger) Warn(args ...interface{}) {
+	the.log(slog.LevelWarn, args...)
+}
+
+func (the *SlogLogger) Error(args ...interface{}) {
+	the.log(slog.LevelError, args...)
+}
+
+func (the *SlogLogger) Fatal(args ...interface{}) {
+	the.log(slogLevelFatal, args...)
+	os.Exit(1)
+}
+
+func (the *SlogLogger) SetLevel(level string) error {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "trace":
+		the.level.Set(slogLevelTrace)
+	case "debug":
+		the.level.Set(slog.LevelDebug)
+	case "info":
+		the.level.Set(slog.LevelInfo)
+	case "warn", "warning":
+		the.level.Set(slog.LevelWarn)
+	case "error":
+		the.level.Set(slog.LevelError)
+	case "fatal", "panic":
+		the.level.Set(slogLevelFatal)
+	default:
+		return fmt.Errorf("not a valid level: %q", level)
+	}
+	return nil
+}
+
+func (the *SlogLogger) GetLevel() string {
+	switch level := the.level.Level(); {
+	case level <= slogLevelTrace:
+		return "trace"
+	case level <= slog.LevelDebug:
+		return "debug"
+	case level <= slog.LevelInfo:
+		return "info"
+	case level <= slog.LevelWarn:
+		return "warning"
+	case level <= slog.LevelError:
+		return "error"
+	default:
+		return "fatal"
+	}
+}
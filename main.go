@@ -24,12 +24,13 @@ var (
 	SettingConfig   Setting
 	CurEnv          string //dev,beta,release
 	CurEnvConfig    EnvConfig
-	StatisticsLog   = logrus.New()
-	AccessLog       = logrus.New()
-	CommonLog       = logrus.New()
-	ErrorLog        = logrus.New()
+	StatisticsLog   Logger = NewLogrusLogger(logrus.New()) //可以在 Init 前替换成其他 Logger 实现
+	AccessLog       Logger = NewLogrusLogger(logrus.New())
+	CommonLog       Logger = NewLogrusLogger(logrus.New())
+	ErrorLog        Logger = NewLogrusLogger(logrus.New())
 	logLevel        string
 	logPath         string
+	logMode         string //file,stdout
 	settingFile     string
 )
 
@@ -52,6 +53,8 @@ func Init(fm map[string]interface{}) {
 	if err := setLog(); err != nil {
 		panic(err)
 	}
+	setLogSampling()
+	watchLogLevel()
 	factoryMap = fm
 	typeMap = make(map[reflect.Type]string)
 	for key, val := range factoryMap {
@@ -75,19 +78,24 @@ func setSetting() error {
 }
 
 func setLog() error {
+	for _, logger := range []Logger{StatisticsLog, AccessLog, CommonLog, ErrorLog} {
+		if err := logger.SetLevel(logLevel); err != nil {
+			return err
+		}
+	}
+	mode := getLogMode()
+	if mode == "stdout" {
+		setStdLog()
+		return nil
+	}
+	if mode != "file" {
+		return errors.New("logMode error: " + mode)
+	}
 	if !exists(logPath) {
 		if err := os.Mkdir(logPath, os.ModePerm); err != nil {
 			return err
 		}
 	}
-	level, levelErr := logrus.ParseLevel(logLevel)
-	if levelErr != nil {
-		return levelErr
-	}
-	StatisticsLog.SetLevel(level)
-	AccessLog.SetLevel(level)
-	CommonLog.SetLevel(level)
-	ErrorLog.SetLevel(level)
 	statisticsLogFile := logPath + separator + "statistics.log"
 	accessLogFile := logPath + separator + "access.log"
 	commonLogFile := logPath + separator + "common.log"
@@ -100,7 +108,13 @@ func setLog() error {
 	`WithRotationCount` 设置文件清理前最多保存的个数
 	*/
 	// 下面配置日志每隔2小时轮转一个新文件，保留最近12个日志文件，多余的自动清理掉。
-	set := func(logger *logrus.Logger, path string, formatter logrus.Formatter) {
+	//非 logrus 实现的 Logger 由使用者自己配置输出
+	set := func(log Logger, path string, formatter logrus.Formatter) {
+		logrusLogger, ok := log.(*LogrusLogger)
+		if !ok {
+			return
+		}
+		logger := logrusLogger.Logrus()
 		writer, _ := rotatelogs.New(
 			path+".%Y%m%d%H%M",
 			rotatelogs.WithLinkName(path),
@@ -131,6 +145,70 @@ func setLog() error {
 	return nil
 }
 
+// 容器模式: 不创建日志目录和文件, 全部以json行输出到 stdout(错误日志到 stderr), 字段 logger 标明日志名, 支持只读根文件系统
+func setStdLog() {
+	set := func(log Logger, name string, out io.Writer) {
+		logrusLogger, ok := log.(*LogrusLogger)
+		if !ok {
+			return
+		}
+		logger := logrusLogger.Logrus()
+		logger.SetFormatter(&logrus.JSONFormatter{
+			TimestampFormat: "2006-01-02 15:04:05",
+		})
+		logger.SetOutput(out)
+		logger.ReplaceHooks(make(logrus.LevelHooks))
+		logger.AddHook(&nameHook{name: name})
+	}
+	set(StatisticsLog, "statistics", os.Stdout)
+	set(AccessLog, "access", os.Stdout)
+	set(CommonLog, "common", os.Stdout)
+	set(ErrorLog, "error", os.Stderr)
+}
+
+type nameHook struct {
+	name string
+}
+
+func (the *nameHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (the *nameHook) Fire(entry *logrus.Entry) error {
+	entry.Data["logger"] = the.name
+	return nil
+}
+
+// 优先级: 命令行 -logMode > SetLogMode > 配置 logMode > 默认 file
+func getLogMode() string {
+	mode := commandLineArg("-logMode")
+	if mode == "" {
+		mode = logMode
+	}
+	if mode == "" {
+		mode = CurEnvConfig.LogMode
+	}
+	if mode == "" {
+		mode = "file"
+	}
+	return strings.ToLower(strings.TrimSpace(mode))
+}
+
+func commandLineArg(name string) string {
+	length := len(os.Args)
+	for i := 1; i < length-1; i++ {
+		if strings.TrimSpace(os.Args[i]) == name {
+			return strings.TrimSpace(os.Args[i+1])
+		}
+	}
+	return ""
+}
+
+// mode: file(默认, 写入日志目录并轮转), stdout(容器模式)
+func SetLogMode(mode string) {
+	logMode = strings.TrimSpace(mode)
+}
+
 func SetLogPath(path string) {
 	reg := regexp.MustCompile(separator + `$`)
 	logPath = reg.ReplaceAllString(strings.TrimSpace(path), "")
@@ -369,6 +447,18 @@ func In(name string) bool {
 	return ok
 }
 
+// FactoryType 取出工厂注册的类型, 不会创建实例
+func FactoryType(name string) reflect.Type {
+	//防止并发写map异常
+	factoryMapGuard.RLock()
+	obj, ok := factoryMap[name]
+	factoryMapGuard.RUnlock()
+	if !ok {
+		return nil
+	}
+	return reflect.TypeOf(obj)
+}
+
 func Factory(name string, args ...interface{}) interface{} {
 	if !In(name) {
 		panic(errors.New("工厂不存在" + name))
@@ -0,0 +1,78 @@
+package mvc
+
+import (
+	"errors"
+	"github.com/luoshanzhi/monster-go"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// AutoRoutes 按约定把工厂里的控制器方法注册成路由, 如 Bird.UploadFiles => prefix/bird/upload-files
+func AutoRoutes(prefix string, names ...string) *Router {
+	router := NewRouter()
+	router.AutoRoutes(prefix, names...)
+	return router
+}
+
+// AutoRoutes 跳过 Init, Use, Multiton; 请求方法取自 mvc.GET, mvc.POST 等标记参数, 没有标记就接受所有方法
+// 路由自动命名为 控制器.方法, 如 Bird.Fly
+func (the *Router) AutoRoutes(prefix string, names ...string) *Router {
+	group := the.Group(prefix)
+	for _, name := range names {
+		objType := monster.FactoryType(name)
+		if objType == nil {
+			panic(errors.New("工厂不存在" + name))
+		}
+		for i, numMethod := 0, objType.NumMethod(); i < numMethod; i++ {
+			method := objType.Method(i)
+			if method.Name == "Init" || method.Name == "Use" || method.Name == "Multiton" {
+				continue
+			}
+			path := "/" + the.formatName(name) + "/" + the.formatName(method.Name)
+			mMap := allowMethods(method.Type)
+			if len(mMap) == 0 {
+				group.Any(path, name, method.Name).Name(name + "." + method.Name)
+				continue
+			}
+			var methods []string
+			for m := range mMap {
+				methods = append(methods, m)
+			}
+			sort.Strings(methods)
+			for j, m := range methods {
+				entry := group.Handle(m, path, name, method.Name)
+				if j == 0 {
+					entry.Name(name + "." + method.Name)
+				} else {
+					entry.name = name + "." + method.Name
+				}
+			}
+		}
+	}
+	return the
+}
+
+func (the *Router) formatName(name string) string {
+	if the.NameCase == CamelCase {
+		return monster.FirstLower(name)
+	}
+	return kebabCase(name)
+}
+
+// UploadFiles => upload-files, HTTPServer => http-server
+func kebabCase(name string) string {
+	runes := []rune(name)
+	var builder strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))) {
+				builder.WriteByte('-')
+			}
+			builder.WriteRune(unicode.ToLower(r))
+		} else {
+			builder.WriteRune(r)
+		}
+	}
+	return builder.String()
+}
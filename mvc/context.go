@@ -0,0 +1,219 @@
+package mvc
+
+import (
+	"net"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	contextPtrType = reflect.TypeOf((*Context)(nil))
+	contextPool    = sync.Pool{
+		New: func() interface{} {
+			return &Context{}
+		},
+	}
+)
+
+// Context 包装 http.ResponseWriter 和 *http.Request, 控制器方法传 *mvc.Context 就会注入
+// 对象从池里取, 请求结束后放回, 不要在控制器方法返回后继续使用, 比如在新开的协程里
+type Context struct {
+	Writer  http.ResponseWriter
+	Request *http.Request
+	server  *Server
+	route   Route
+	values  map[string]interface{}
+	query   map[string][]string
+}
+
+// 同一个请求只创建一次, 多个 *mvc.Context 参数拿到的是同一个
+func acquireContext(server *Server, w http.ResponseWriter, req *http.Request, route Route) *Context {
+	state := stateOf(req)
+	if state != nil && state.ctx != nil {
+		return state.ctx
+	}
+	ctx := contextPool.Get().(*Context)
+	ctx.Writer = w
+	ctx.Request = req
+	ctx.server = server
+	ctx.route = route
+	if state != nil {
+		state.ctx = ctx
+	}
+	return ctx
+}
+
+// 清空后放回池里, values 保留已经分配的空间
+func releaseContext(ctx *Context) {
+	for key := range ctx.values {
+		delete(ctx.values, key)
+	}
+	ctx.Writer = nil
+	ctx.Request = nil
+	ctx.server = nil
+	ctx.route = Route{}
+	ctx.query = nil
+	contextPool.Put(ctx)
+}
+
+// Route 匹配到的路由
+func (the *Context) Route() Route {
+	return the.route
+}
+
+// Query url 上的参数, 没有返回空字符串
+func (the *Context) Query(name string) string {
+	if the.query == nil {
+		the.query = the.Request.URL.Query()
+	}
+	if values := the.query[name]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// QueryInt url 上的整数参数, 没有或格式错误返回 def
+func (the *Context) QueryInt(name string, def int) int {
+	return atoi(the.Query(name), def)
+}
+
+// Param 路径参数, 如 /users/{id} 里的 id
+func (the *Context) Param(name string) string {
+	return the.route.Params[name]
+}
+
+// ParamInt 整数路径参数, 没有或格式错误返回 def
+func (the *Context) ParamInt(name string, def int) int {
+	return atoi(the.Param(name), def)
+}
+
+func (the *Context) Header(name string) string {
+	return the.Request.Header.Get(name)
+}
+
+func (the *Context) SetHeader(name string, value string) {
+	the.Writer.Header().Set(name, value)
+}
+
+// Cookie 没有返回空字符串
+func (the *Context) Cookie(name string) string {
+	cookie, err := the.Request.Cookie(name)
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
+
+// SetCookie Path 为空时默认 /, 要在返回视图之前调用
+func (the *Context) SetCookie(cookie *http.Cookie) {
+	if cookie.Path == "" {
+		cookie.Path = "/"
+	}
+	http.SetCookie(the.Writer, cookie)
+}
+
+// ClientIP 客户端 ip, 只有直接连接的地址在 Server.TrustedProxies 里才认 X-Forwarded-For 和 X-Real-IP
+// X-Forwarded-For 从右往左跳过可信代理, 取第一个不可信的地址, 防止客户端伪造
+func (the *Context) ClientIP() string {
+	remoteIP := the.Request.RemoteAddr
+	if host, _, err := net.SplitHostPort(remoteIP); err == nil {
+		remoteIP = host
+	}
+	if the.server == nil || !the.server.trusted(remoteIP) {
+		return remoteIP
+	}
+	var forwarded []string
+	for _, header := range the.Request.Header.Values("X-Forwarded-For") {
+		for _, ip := range strings.Split(header, ",") {
+			if ip = strings.TrimSpace(ip); ip != "" {
+				forwarded = append(forwarded, ip)
+			}
+		}
+	}
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		if net.ParseIP(forwarded[i]) == nil {
+			break
+		}
+		if i == 0 || !the.server.trusted(forwarded[i]) {
+			return forwarded[i]
+		}
+	}
+	if ip := strings.TrimSpace(the.Request.Header.Get("X-Real-IP")); net.ParseIP(ip) != nil {
+		return ip
+	}
+	return remoteIP
+}
+
+// Set 保存只在这个请求里用的值
+func (the *Context) Set(key string, value interface{}) {
+	if the.values == nil {
+		the.values = make(map[string]interface{})
+	}
+	the.values[key] = value
+}
+
+func (the *Context) Get(key string) (interface{}, bool) {
+	value, ok := the.values[key]
+	return value, ok
+}
+
+// JSON 返回 json 视图, status 不传默认 200
+func (the *Context) JSON(data interface{}, status ...int) *JsonView {
+	view := JSON(data)
+	if len(status) > 0 {
+		view.Status = status[0]
+	}
+	return view
+}
+
+// HTML 返回模板视图, status 不传默认 200
+func (the *Context) HTML(name string, data interface{}, status ...int) *HTMLView {
+	view := HTML(name, data)
+	if len(status) > 0 {
+		view.Status = status[0]
+	}
+	return view
+}
+
+func (the *Context) Redirect(url string, status ...int) *RedirectView {
+	return Redirect(url, status...)
+}
+
+func atoi(val string, def int) int {
+	if num, err := strconv.Atoi(strings.TrimSpace(val)); err == nil {
+		return num
+	}
+	return def
+}
+
+// 解析一次 Server.TrustedProxies, 可以是 ip 或网段
+func (the *Server) trusted(ip string) bool {
+	the.trustedOnce.Do(func() {
+		for _, item := range the.TrustedProxies {
+			item = strings.TrimSpace(item)
+			if !strings.Contains(item, "/") {
+				if strings.Contains(item, ":") {
+					item += "/128"
+				} else {
+					item += "/32"
+				}
+			}
+			if _, ipNet, err := net.ParseCIDR(item); err == nil {
+				the.trustedNets = append(the.trustedNets, ipNet)
+			}
+		}
+	})
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, ipNet := range the.trustedNets {
+		if ipNet.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package mvc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted := []string{"10.0.0.0/8", "192.168.1.1", "::1"}
+	tests := []struct {
+		name      string
+		proxies   []string
+		remote    string
+		forwarded []string
+		realIP    string
+		want      string
+	}{
+		{"没有代理", nil, "1.2.3.4:5678", nil, "", "1.2.3.4"},
+		{"没有端口", nil, "1.2.3.4", nil, "", "1.2.3.4"},
+		{"不可信的地址伪造 X-Forwarded-For", trusted, "1.2.3.4:5678", []string{"9.9.9.9"}, "", "1.2.3.4"},
+		{"不可信的地址伪造 X-Real-IP", trusted, "1.2.3.4:5678", nil, "9.9.9.9", "1.2.3.4"},
+		{"没有配置可信代理", nil, "10.0.0.1:80", []string{"9.9.9.9"}, "", "10.0.0.1"},
+		{"可信代理", trusted, "10.0.0.1:80", []string{"1.2.3.4"}, "", "1.2.3.4"},
+		{"多级可信代理", trusted, "10.0.0.1:80", []string{"1.2.3.4, 192.168.1.1, 10.0.0.2"}, "", "1.2.3.4"},
+		{"多个 X-Forwarded-For 头", trusted, "10.0.0.1:80", []string{"1.2.3.4", "10.0.0.2"}, "", "1.2.3.4"},
+		{"客户端在最左边伪造", trusted, "10.0.0.1:80", []string{"9.9.9.9, 1.2.3.4, 10.0.0.2"}, "", "1.2.3.4"},
+		{"全是可信代理取最左边", trusted, "10.0.0.1:80", []string{"10.0.0.3, 10.0.0.2"}, "", "10.0.0.3"},
+		{"格式错误的地址", trusted, "10.0.0.1:80", []string{"unknown, 10.0.0.2"}, "", "10.0.0.1"},
+		{"可信代理用 X-Real-IP", trusted, "10.0.0.1:80", nil, "1.2.3.4", "1.2.3.4"},
+		{"X-Real-IP 格式错误", trusted, "10.0.0.1:80", nil, "unknown", "10.0.0.1"},
+		{"ipv6 可信代理", trusted, "[::1]:80", []string{"2001:db8::1"}, "", "2001:db8::1"},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = test.remote
+		for _, header := range test.forwarded {
+			req.Header.Add("X-Forwarded-For", header)
+		}
+		if test.realIP != "" {
+			req.Header.Set("X-Real-IP", test.realIP)
+		}
+		ctx := &Context{Request: req, server: &Server{TrustedProxies: test.proxies}}
+		if ip := ctx.ClientIP(); ip != test.want {
+			t.Errorf("%s: got %s, want %s", test.name, ip, test.want)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package mvc
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Converter 把参数字符串转换成指定类型, 用 RegisterConverter 注册
+type Converter func(val string) (reflect.Value, error)
+
+var (
+	converters          sync.Map //reflect.Type => Converter
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	jsonUnmarshalType   = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+// RegisterConverter 注册自定义类型的转换, 优先于内置转换, 返回值类型必须可以赋值给 typ, 在 mvc.Serve 之前注册
+// 如 mvc.RegisterConverter(reflect.TypeOf(decimal.Decimal{}), func(val string) (reflect.Value, error) {...})
+func RegisterConverter(typ reflect.Type, converter Converter) {
+	converters.Store(typ, converter)
+}
+
+// 自定义转换, time.Time, time.Duration, encoding.TextUnmarshaler, json.Unmarshaler, 不是这些类型返回 false
+// time.Time 按 layout 标签解析, 默认 time.RFC3339
+func convertSpecial(value reflect.Value, val string, layout string) (bool, error) {
+	typ := value.Type()
+	if converter, ok := converters.Load(typ); ok {
+		newValue, err := converter.(Converter)(val)
+		if err != nil {
+			return true, err
+		}
+		if !newValue.IsValid() || !newValue.Type().AssignableTo(typ) {
+			return true, errors.New("converter returns " + newValue.String() + ", want " + typ.String())
+		}
+		value.Set(newValue)
+		return true, nil
+	}
+	switch {
+	case typ == timeType:
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		t, err := time.ParseInLocation(layout, val, time.Local)
+		if err == nil {
+			value.Set(reflect.ValueOf(t))
+		}
+		return true, err
+	case typ == durationType:
+		d, err := time.ParseDuration(val)
+		if err == nil {
+			value.Set(reflect.ValueOf(d))
+		}
+		return true, err
+	case reflect.PtrTo(typ).Implements(textUnmarshalerType):
+		return true, value.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(val))
+	case reflect.PtrTo(typ).Implements(jsonUnmarshalType):
+		unmarshaler := value.Addr().Interface().(json.Unmarshaler)
+		//先当作 json, 不行再当作 json 字符串
+		if err := unmarshaler.UnmarshalJSON([]byte(val)); err == nil {
+			return true, nil
+		}
+		content, _ := json.Marshal(val)
+		return true, unmarshaler.UnmarshalJSON(content)
+	}
+	return false, nil
+}
+
+// 能直接从一个字符串转换的类型, 比如 net.IP 虽然是切片也按一个值处理
+func isConvertible(typ reflect.Type) bool {
+	if _, ok := converters.Load(typ); ok {
+		return true
+	}
+	return typ == timeType || typ == durationType || reflect.PtrTo(typ).Implements(textUnmarshalerType) || reflect.PtrTo(typ).Implements(jsonUnmarshalType)
+}
+
+// 文件等不是字符串的值, 类型对得上才赋值
+func assignItem(value reflect.Value, item interface{}) error {
+	itemValue := reflect.ValueOf(item)
+	if !itemValue.IsValid() || !itemValue.Type().AssignableTo(value.Type()) {
+		return fmt.Errorf("cannot assign %T to %s", item, value.Type().String())
+	}
+	value.Set(itemValue)
+	return nil
+}
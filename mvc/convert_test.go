@@ -0,0 +1,75 @@
+package mvc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type convertEmbed struct {
+	Name string
+}
+
+type convertParam struct {
+	convertEmbed
+	secret   string
+	hidden   string    `default:"x"`
+	Birthday time.Time `layout:"2006-01-02"`
+	Timeout  time.Duration
+	Public   string
+}
+
+func (the *testController) Convert(param convertParam) *JsonView {
+	return JSON(map[string]interface{}{
+		"name":     param.Name,
+		"public":   param.Public,
+		"secret":   param.secret,
+		"hidden":   param.hidden,
+		"birthday": param.Birthday.Format("2006-01-02"),
+		"timeout":  param.Timeout.String(),
+	})
+}
+
+func TestConvert(t *testing.T) {
+	server := testServer(http.MethodGet, "/convert", "Convert")
+	w := serve(server, httptest.NewRequest(http.MethodGet, "/convert?name=n&public=p&birthday=2020-01-02&timeout=1m30s", nil))
+	var data map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &data); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("status %d, body %s", w.Code, w.Body.String())
+	}
+	if data["name"] != "n" || data["public"] != "p" || data["birthday"] != "2020-01-02" || data["timeout"] != "1m30s" {
+		t.Fatalf("data = %v", data)
+	}
+}
+
+// 不导出的字段忽略, 不能 panic
+func TestConvertUnexported(t *testing.T) {
+	server := testServer(http.MethodGet, "/convert", "Convert")
+	w := serve(server, httptest.NewRequest(http.MethodGet, "/convert?secret=zz&hidden=1&public=p", nil))
+	var data map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &data); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("status %d, body %s", w.Code, w.Body.String())
+	}
+	if data["secret"] != "" || data["hidden"] != "" || data["public"] != "p" {
+		t.Fatalf("data = %v", data)
+	}
+}
+
+// 转换失败在严格模式下输出 400, 不是 panic
+func TestConvertStrict(t *testing.T) {
+	server := testServer(http.MethodGet, "/convert", "Convert")
+	server.StrictBinding = true
+	w := serve(server, httptest.NewRequest(http.MethodGet, "/convert?birthday=2020/01/02&timeout=abc", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status %d, body %s", w.Code, w.Body.String())
+	}
+	var data struct {
+		Errors []FieldError `json:"errors"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &data)
+	if len(data.Errors) != 2 {
+		t.Fatalf("errors = %v", data.Errors)
+	}
+}
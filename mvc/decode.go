@@ -0,0 +1,316 @@
+package mvc
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	decoders = map[string]Decoder{
+		"application/json": JsonDecoder{},
+		"application/xml":  XmlDecoder{},
+		"text/xml":         XmlDecoder{},
+	}
+	decodersGuard sync.RWMutex
+)
+
+type JsonDecoder struct{}
+
+// 严格模式下不认识的字段, 数组装不下也算错误, 返回 *ValidationError 列出每个出错的字段
+func (the JsonDecoder) Decode(body []byte, obj interface{}, strict bool) error {
+	if !strict {
+		return json.Unmarshal(body, obj)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(obj)
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return err
+	}
+	//encoding/json 只返回第一个错误, 也不检查数组长度, 重新按类型检查一遍
+	var raw interface{}
+	rawDecoder := json.NewDecoder(bytes.NewReader(body))
+	rawDecoder.UseNumber()
+	if rawDecoder.Decode(&raw) == nil {
+		var fieldErrors []FieldError
+		checkJson(raw, reflect.TypeOf(obj), "", &fieldErrors)
+		if len(fieldErrors) > 0 {
+			return &ValidationError{Errors: fieldErrors}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	//和 json.Unmarshal 一样, 后面不能还有别的内容
+	if _, err := decoder.Token(); err != io.EOF {
+		return errors.New("invalid character after top-level value")
+	}
+	return nil
+}
+
+// 按 typ 检查 json 解析出来的值, 类型不对, 不认识的字段, 数组装不下都记录到 fieldErrors, label 是 a.b.0 这样的路径
+// 自己实现了 json.Unmarshaler 或 encoding.TextUnmarshaler 的类型交给 encoding/json 判断
+func checkJson(raw interface{}, typ reflect.Type, label string, fieldErrors *[]FieldError) {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if raw == nil || typ.Kind() == reflect.Interface || reflect.PtrTo(typ).Implements(jsonUnmarshalType) {
+		return
+	}
+	if _, ok := raw.(string); ok && reflect.PtrTo(typ).Implements(textUnmarshalerType) {
+		return
+	}
+	typeError := func() {
+		*fieldErrors = append(*fieldErrors, FieldError{Field: label, Rule: "type", Msg: "类型错误"})
+	}
+	join := func(name string) string {
+		if label == "" {
+			return name
+		}
+		return label + "." + name
+	}
+	switch typ.Kind() {
+	case reflect.Struct:
+		object, ok := raw.(map[string]interface{})
+		if !ok {
+			typeError()
+			return
+		}
+		fields := jsonFields(typ)
+		for name, value := range object {
+			field, ok := fields[name]
+			if !ok {
+				//和 encoding/json 一样, 名字不区分大小写
+				for key, item := range fields {
+					if strings.EqualFold(key, name) {
+						field, ok = item, true
+						break
+					}
+				}
+			}
+			if !ok {
+				*fieldErrors = append(*fieldErrors, FieldError{Field: join(name), Rule: "unknown", Msg: "不支持的字段"})
+				continue
+			}
+			if _, isString := value.(string); isString && field.quoted {
+				continue
+			}
+			checkJson(value, field.typ, join(name), fieldErrors)
+		}
+	case reflect.Map:
+		object, ok := raw.(map[string]interface{})
+		if !ok {
+			typeError()
+			return
+		}
+		for name, value := range object {
+			checkJson(value, typ.Elem(), join(name), fieldErrors)
+		}
+	case reflect.Slice, reflect.Array:
+		if _, ok := raw.(string); ok && typ.Kind() == reflect.Slice && typ.Elem().Kind() == reflect.Uint8 {
+			return
+		}
+		list, ok := raw.([]interface{})
+		if !ok {
+			typeError()
+			return
+		}
+		if typ.Kind() == reflect.Array && len(list) > typ.Len() {
+			*fieldErrors = append(*fieldErrors, FieldError{Field: label, Rule: "overflow", Msg: "最多 " + strconv.Itoa(typ.Len()) + " 个"})
+			list = list[:typ.Len()]
+		}
+		for i, value := range list {
+			checkJson(value, typ.Elem(), join(strconv.Itoa(i)), fieldErrors)
+		}
+	case reflect.String:
+		if _, ok := raw.(string); !ok {
+			typeError()
+		}
+	case reflect.Bool:
+		if _, ok := raw.(bool); !ok {
+			typeError()
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		number, ok := raw.(json.Number)
+		if !ok {
+			typeError()
+		} else if _, err := strconv.ParseInt(string(number), 10, typ.Bits()); err != nil {
+			typeError()
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		number, ok := raw.(json.Number)
+		if !ok {
+			typeError()
+		} else if _, err := strconv.ParseUint(string(number), 10, typ.Bits()); err != nil {
+			typeError()
+		}
+	case reflect.Float32, reflect.Float64:
+		number, ok := raw.(json.Number)
+		if !ok {
+			typeError()
+		} else if _, err := strconv.ParseFloat(string(number), typ.Bits()); err != nil {
+			typeError()
+		}
+	}
+}
+
+type jsonField struct {
+	typ    reflect.Type
+	quoted bool //json:",string"
+}
+
+// 结构里 json 能赋值的字段, 按 json 名字, 内嵌匿名结构的字段提升上来
+// 指定了来源(path, query, form, header, cookie)的字段不从 body 取值, 不算在里面
+func jsonFields(typ reflect.Type) map[string]jsonField {
+	fields := make(map[string]jsonField)
+	var walk func(structType reflect.Type)
+	walk = func(structType reflect.Type) {
+		for i, numField := 0, structType.NumField(); i < numField; i++ {
+			structField := structType.Field(i)
+			tag := structField.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			options := strings.Split(tag, ",")
+			name := options[0]
+			fieldType := structField.Type
+			if structField.Anonymous && name == "" {
+				if fieldType.Kind() == reflect.Ptr {
+					fieldType = fieldType.Elem()
+				}
+				if fieldType.Kind() == reflect.Struct {
+					walk(fieldType)
+					continue
+				}
+			}
+			if structField.PkgPath != "" || hasSourceTag(structField) {
+				continue
+			}
+			if name == "" {
+				name = structField.Name
+			}
+			//外层的字段优先
+			if _, ok := fields[name]; !ok {
+				fields[name] = jsonField{typ: fieldType, quoted: inStrings(options[1:], "string")}
+			}
+		}
+	}
+	walk(typ)
+	return fields
+}
+
+type XmlDecoder struct{}
+
+func (the XmlDecoder) Decode(body []byte, obj interface{}, strict bool) error {
+	return xml.Unmarshal(body, obj)
+}
+
+// RegisterDecoder 注册请求 body 的解析方式, 如 application/msgpack, Server.Decoders 里的优先
+func RegisterDecoder(contentType string, decoder Decoder) {
+	contentType = strings.ToLower(strings.TrimSpace(contentType))
+	//防止并发写map异常
+	decodersGuard.Lock()
+	decoders[contentType] = decoder
+	decodersGuard.Unlock()
+}
+
+// 按 Content-Type 找解析方式, application/problem+json 这样的后缀也认, 没有返回 nil
+func findDecoder(server *Server, req *http.Request) Decoder {
+	contentType := req.Header.Get("Content-Type")
+	if contentType == "" {
+		return nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil
+	}
+	var types = []string{mediaType}
+	if i := strings.LastIndex(mediaType, "+"); i != -1 && strings.Contains(mediaType, "/") {
+		types = append(types, "application/"+mediaType[i+1:])
+	}
+	for _, item := range types {
+		if server != nil {
+			if decoder, ok := server.Decoders[item]; ok {
+				return decoder
+			}
+		}
+		decodersGuard.RLock()
+		decoder, ok := decoders[item]
+		decodersGuard.RUnlock()
+		if ok {
+			return decoder
+		}
+	}
+	return nil
+}
+
+// 不是注册过的 Content-Type 返回 false, 读取失败返回 *Error, 解析失败返回 Decoder 的错误
+func parseBody(server *Server, req *http.Request, obj interface{}, strict bool) (bool, error) {
+	decoder := findDecoder(server, req)
+	if decoder == nil {
+		return false, nil
+	}
+	body, err := Body(req)
+	if err != nil {
+		return false, bodyError(err)
+	}
+	if err := decoder.Decode(body, obj, strict); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ErrStreamBody 上传文件(multipart)和断点续传(application/offset+octet-stream)的 body 可能很大, mvc.Body 不读到内存里
+var ErrStreamBody = errors.New("mvc: multipart and application/offset+octet-stream bodies are streamed, not buffered")
+
+type bodyCache struct {
+	once sync.Once
+	read bool
+	body []byte
+	err  error
+}
+
+// Body 读取请求 body, 同一个请求只读一次, 拦截器和控制器都可以调用, 超过 Limits.MaxBodySize 返回 *http.MaxBytesError
+// 读完后 req.Body 换成可以重新读取的内容, 不影响 req.ParseForm 等
+// 前置拦截器在路由之前, 这时只按 Server.Limits 限制, 路由上放大的 MaxBodySize 不生效
+// 上传文件和断点续传的 body 不读, 返回 ErrStreamBody, 交给 parseParam 和 ResumableUpload 按路由的限制边读边处理
+func Body(req *http.Request) ([]byte, error) {
+	if isStreamBody(req) {
+		return nil, ErrStreamBody
+	}
+	cache := &bodyCache{}
+	var limits Limits
+	if state := stateOf(req); state != nil {
+		cache = &state.body
+		limits = state.limits
+	}
+	cache.once.Do(func() {
+		cache.read = true
+		if req.Body != nil && req.Body != http.NoBody {
+			//路由前的拦截器读取时还没有按路由限制, 这里按 Server.Limits 限制
+			reader := req.Body
+			if size := limits.maxBodySize(); size > 0 {
+				reader = http.MaxBytesReader(nil, req.Body, size)
+			}
+			cache.body, cache.err = io.ReadAll(reader)
+			req.Body.Close()
+		}
+	})
+	req.Body = io.NopCloser(bytes.NewReader(cache.body))
+	return cache.body, cache.err
+}
+
+func isStreamBody(req *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	return strings.HasPrefix(mediaType, "multipart/") || mediaType == "application/offset+octet-stream"
+}
@@ -0,0 +1,150 @@
+package mvc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+type decodeInner struct {
+	Code string `json:"code"`
+}
+
+type decodeParam struct {
+	Num   int           `json:"num"`
+	Arr   [2]int        `json:"arr"`
+	Str   string        `json:"str"`
+	Inner decodeInner   `json:"inner"`
+	Items []decodeInner `json:"items"`
+}
+
+func (the *testController) Decode(param decodeParam) *JsonView {
+	return JSON(param)
+}
+
+// 请求 json, 返回状态码和严格模式下出错的字段
+func postJson(t *testing.T, server *Server, body string) (int, []string) {
+	req := httptest.NewRequest(http.MethodPost, "/decode", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := serve(server, req)
+	var data struct {
+		Errors []FieldError `json:"errors"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &data)
+	var fields []string
+	for _, item := range data.Errors {
+		fields = append(fields, item.Field+":"+item.Rule)
+	}
+	sort.Strings(fields)
+	return w.Code, fields
+}
+
+func TestDecodeJson(t *testing.T) {
+	server := testServer(http.MethodPost, "/decode", "Decode")
+	req := httptest.NewRequest(http.MethodPost, "/decode", strings.NewReader(`{"num":1,"arr":[1,2],"inner":{"code":"abc"}}`))
+	req.Header.Set("Content-Type", "application/problem+json")
+	w := serve(server, req)
+	var param decodeParam
+	if err := json.Unmarshal(w.Body.Bytes(), &param); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("status %d, body %s", w.Code, w.Body.String())
+	}
+	if param.Num != 1 || param.Arr != [2]int{1, 2} || param.Inner.Code != "abc" {
+		t.Fatalf("param = %+v", param)
+	}
+}
+
+// 非严格模式下格式错误的 json 不报错
+func TestDecodeLoose(t *testing.T) {
+	server := testServer(http.MethodPost, "/decode", "Decode")
+	if status, _ := postJson(t, server, `{"num":"x"`); status != http.StatusOK {
+		t.Fatalf("status %d", status)
+	}
+}
+
+func TestDecodeStrict(t *testing.T) {
+	server := testServer(http.MethodPost, "/decode", "Decode")
+	server.StrictBinding = true
+	tests := []struct {
+		body   string
+		status int
+		fields string
+	}{
+		{`{"num":1,"arr":[1,2]}`, http.StatusOK, ""},
+		{`{"arr":[1,2,3]}`, http.StatusBadRequest, "arr:overflow"},
+		{`{"num":"x","arr":"y"}`, http.StatusBadRequest, "arr:type,num:type"},
+		{`{"num":1,"other":1,"inner":{"code":1,"x":2}}`, http.StatusBadRequest, "inner.code:type,inner.x:unknown,other:unknown"},
+		{`{"items":[{"code":"a"},{"code":true}]}`, http.StatusBadRequest, "items.1.code:type"},
+		{`{"num":99999999999999999999}`, http.StatusBadRequest, "num:type"},
+		{`{"num":1,}`, http.StatusBadRequest, "body:json"},
+		{`{"num":1`, http.StatusBadRequest, "body:decode"},
+		{`{"num":1} {}`, http.StatusBadRequest, "body:decode"},
+	}
+	for _, test := range tests {
+		status, fields := postJson(t, server, test.body)
+		if status != test.status || strings.Join(fields, ",") != test.fields {
+			t.Errorf("%s: status %d, fields %v, want %d %s", test.body, status, fields, test.status, test.fields)
+		}
+	}
+}
+
+// 前置拦截器读取 body 不影响控制器取参数
+type bodyInterceptor struct {
+	body []byte
+	err  error
+}
+
+func (the *bodyInterceptor) Before(w http.ResponseWriter, req *http.Request, throughout []reflect.Value, throughoutIndex int) View {
+	the.body, the.err = Body(req)
+	return nil
+}
+
+func (the *bodyInterceptor) Invoke(w http.ResponseWriter, req *http.Request, method reflect.Value, in []reflect.Value, throughout []reflect.Value, throughoutIndex int) View {
+	return nil
+}
+
+func (the *bodyInterceptor) After(w http.ResponseWriter, req *http.Request, ret reflect.Value, throughout []reflect.Value, throughoutIndex int) View {
+	return nil
+}
+
+func TestBodyInInterceptor(t *testing.T) {
+	interceptor := &bodyInterceptor{}
+	server := testServer(http.MethodPost, "/decode", "Decode")
+	server.Interceptors = []Interceptor{interceptor}
+	req := httptest.NewRequest(http.MethodPost, "/decode", strings.NewReader(`{"num":3}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := serve(server, req)
+	var param decodeParam
+	json.Unmarshal(w.Body.Bytes(), &param)
+	if string(interceptor.body) != `{"num":3}` || param.Num != 3 {
+		t.Fatalf("interceptor body %q, param %+v", interceptor.body, param)
+	}
+}
+
+// 上传文件的 body 不读到内存, 按路由的 Limits 处理
+func TestBodyStream(t *testing.T) {
+	interceptor := &bodyInterceptor{}
+	router := NewRouter()
+	router.Post("/upload", "Test", "Validate").Limits(Limits{MaxBodySize: 4 << 20})
+	server := &Server{Handler: router.Handler, Interceptors: []Interceptor{interceptor}, Limits: Limits{MaxBodySize: 1 << 20}}
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	writer.WriteField("name", "abc")
+	writer.WriteField("score", "1")
+	writer.WriteField("inner[code]", "xyz")
+	part, _ := writer.CreateFormFile("file", "a.bin")
+	part.Write(make([]byte, 2<<20))
+	writer.Close()
+	req := httptest.NewRequest(http.MethodPost, "/upload", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	w := serve(server, req)
+	if !errors.Is(interceptor.err, ErrStreamBody) || w.Code != http.StatusOK {
+		t.Fatalf("interceptor err %v, status %d, body %s", interceptor.err, w.Code, w.Body.String())
+	}
+}
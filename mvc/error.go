@@ -0,0 +1,163 @@
+package mvc
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/luoshanzhi/monster-go"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// ErrNotFound 路由不存在, 输出 404
+var ErrNotFound = errors.New("错误的路由")
+
+// MethodNotAllowedError 路由存在但不接受该请求方法, 输出 405 和 Allow 头, OPTIONS 请求直接输出 Allow
+type MethodNotAllowedError struct {
+	Allow []string
+}
+
+func (the *MethodNotAllowedError) Error() string {
+	return "错误的路由请求类型"
+}
+
+// Error 带状态码的错误, 控制器返回后由 Server.ErrorHandler 输出
+type Error struct {
+	Status int
+	Msg    string
+	Err    error //原始错误, 不会输出给客户端
+}
+
+func (the *Error) Error() string {
+	if the.Err != nil {
+		return the.Msg + ": " + the.Err.Error()
+	}
+	return the.Msg
+}
+
+func (the *Error) Unwrap() error {
+	return the.Err
+}
+
+func NewError(status int, msg string, err ...error) *Error {
+	e := &Error{Status: status, Msg: msg}
+	if len(err) > 0 {
+		e.Err = err[0]
+	}
+	return e
+}
+
+// BadRequest 参数校验等错误, 400
+func BadRequest(msg string, err ...error) *Error {
+	return NewError(http.StatusBadRequest, msg, err...)
+}
+
+func Unauthorized(msg string, err ...error) *Error {
+	return NewError(http.StatusUnauthorized, msg, err...)
+}
+
+func Forbidden(msg string, err ...error) *Error {
+	return NewError(http.StatusForbidden, msg, err...)
+}
+
+func NotFound(msg string, err ...error) *Error {
+	return NewError(http.StatusNotFound, msg, err...)
+}
+
+// TooLarge 请求内容超过 Limits, 413
+func TooLarge(msg string, err ...error) *Error {
+	return NewError(http.StatusRequestEntityTooLarge, msg, err...)
+}
+
+// StatusOf 取出错误对应的状态码, 未知错误为 500
+func StatusOf(err error) int {
+	var e *Error
+	var methodErr *MethodNotAllowedError
+	var validationErr *ValidationError
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &e) {
+		return e.Status
+	} else if errors.As(err, &validationErr) {
+		return http.StatusBadRequest
+	} else if errors.As(err, &maxErr) {
+		return http.StatusRequestEntityTooLarge
+	} else if errors.As(err, &methodErr) {
+		return http.StatusMethodNotAllowed
+	} else if errors.Is(err, ErrNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// DefaultErrorHandler 默认错误输出: {"code":状态码,"msg":"错误信息"}, 参数错误带上 "errors":[{"field","rule","msg"}]
+// 未知错误记录到错误日志, release 环境不把原始错误输出给客户端
+func DefaultErrorHandler(w http.ResponseWriter, req *http.Request, err error) {
+	status := StatusOf(err)
+	msg := err.Error()
+	var e *Error
+	if errors.As(err, &e) {
+		msg = e.Msg
+	}
+	if status >= http.StatusInternalServerError {
+		monster.ErrorLog.Error(req.URL.Path+":", err)
+		if monster.CurEnv == "release" {
+			msg = "请求异常"
+		}
+	}
+	data := map[string]interface{}{
+		"code": status,
+		"msg":  msg,
+	}
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		data["msg"] = "参数错误"
+		data["errors"] = validationErr.Errors
+	}
+	content, jsonErr := json.Marshal(data)
+	if jsonErr != nil {
+		ResponseOut(w, http.StatusInternalServerError, nil, "请求异常")
+		return
+	}
+	header := map[string]string{
+		"Content-Type": "application/json; charset=utf-8",
+	}
+	ResponseOut(w, status, header, string(content))
+}
+
+func errorOut(server *Server, w http.ResponseWriter, req *http.Request, err error) {
+	if server.ErrorHandler != nil {
+		server.ErrorHandler(w, req, err)
+	} else {
+		DefaultErrorHandler(w, req, err)
+	}
+}
+
+// 没有标记请求方法时 Allow 头里列出的方法
+var defaultAllowMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodOptions: true,
+}
+
+// 生成 Allow 头: 允许 GET 就允许 HEAD, 总是允许 OPTIONS
+func allowHeader(mMap map[string]bool) string {
+	if len(mMap) == 0 {
+		mMap = defaultAllowMethods
+	}
+	var methods []string
+	for method := range mMap {
+		methods = append(methods, method)
+	}
+	if mMap["GET"] && !mMap["HEAD"] {
+		methods = append(methods, "HEAD")
+	}
+	if !mMap["OPTIONS"] {
+		methods = append(methods, "OPTIONS")
+	}
+	sort.Strings(methods)
+	return strings.Join(methods, ", ")
+}
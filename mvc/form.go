@@ -0,0 +1,205 @@
+package mvc
+
+import (
+	"mime/multipart"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 表单嵌套取值, 参数名支持这些写法:
+// user[name], user.address.city  结构或者 map[string]T
+// items[1][qty], items[1].qty    结构切片, 下标只用来排序和分组, 不会按下标创建空元素
+// tags[]                         普通切片, 和 tags 一样
+type formNode struct {
+	values   []interface{} //string 或 *multipart.FileHeader
+	children map[string]*formNode
+}
+
+// 结构(不包括文件和能从字符串转换的类型), 结构指针, map, 结构切片需要按嵌套参数名取值
+func isNested(typ reflect.Type) bool {
+	if isFile(typ) {
+		return false
+	}
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if isConvertible(typ) {
+		return false
+	}
+	switch typ.Kind() {
+	case reflect.Struct:
+		return true
+	case reflect.Map:
+		return true
+	case reflect.Slice, reflect.Array:
+		return isNested(typ.Elem())
+	}
+	return false
+}
+
+// 按参数名拆成路径: items[1][qty] => items 1 qty, user.address.city => user address city, tags[] => tags
+func splitFormKey(key string) []string {
+	var path []string
+	for _, part := range strings.Split(key, ".") {
+		for part != "" {
+			i := strings.Index(part, "[")
+			if i == -1 {
+				path = append(path, part)
+				break
+			}
+			if i > 0 {
+				path = append(path, part[:i])
+			}
+			j := strings.Index(part[i:], "]")
+			if j == -1 {
+				path = append(path, part[i:])
+				break
+			}
+			if name := part[i+1 : i+j]; name != "" {
+				path = append(path, name)
+			}
+			part = part[i+j+1:]
+		}
+	}
+	return path
+}
+
+func (the *formNode) child(name string) *formNode {
+	if the == nil {
+		return nil
+	}
+	return the.children[name]
+}
+
+func (the *formNode) add(key string, values []interface{}) {
+	node := the
+	for _, name := range splitFormKey(key) {
+		if node.children == nil {
+			node.children = make(map[string]*formNode)
+		}
+		next, ok := node.children[name]
+		if !ok {
+			next = &formNode{}
+			node.children[name] = next
+		}
+		node = next
+	}
+	if node != the {
+		//后加的来源优先, 和 post 区优先于 get 区一致
+		node.values = values
+	}
+}
+
+// 按顺序加入, 后面的来源覆盖前面同名的参数
+func buildForm(vals []url.Values, file map[string][]*multipart.FileHeader) *formNode {
+	root := &formNode{}
+	for _, item := range vals {
+		for key, values := range item {
+			if len(values) > 0 {
+				root.add(key, stringList(values))
+			}
+		}
+	}
+	for key, files := range file {
+		if len(files) > 0 {
+			root.add(key, fileList(files))
+		}
+	}
+	return root
+}
+
+// 把节点的值放进 value, 严格模式下转换错误记录到 fieldErrors, label 是错误里的字段名
+func bindNode(value reflect.Value, typ reflect.Type, node *formNode, label string, layout string, explicit bool, fieldErrors *[]FieldError) {
+	if node == nil {
+		return
+	}
+	if !isNested(typ) {
+		if len(node.values) == 0 {
+			return
+		}
+		if err := setList(value, typ, node.values, layout); err != nil {
+			*fieldErrors = append(*fieldErrors, bindFieldError(&paramField{typ: typ, label: label}, err))
+		}
+		return
+	}
+	if typ.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			value.Set(reflect.New(typ.Elem()))
+		}
+		value, typ = value.Elem(), typ.Elem()
+	}
+	switch typ.Kind() {
+	case reflect.Struct:
+		plan := getParamPlan(typ)
+		newEmbeds(plan, value)
+		for i := range plan.fields {
+			field := &plan.fields[i]
+			names := field.names
+			if field.source == "form" || field.source == "query" {
+				names = []string{field.key}
+			} else if field.source != "" {
+				continue
+			} else if explicit {
+				names = nil
+				if field.jsonName != "" {
+					names = []string{field.jsonName}
+				}
+			}
+			for _, name := range names {
+				if child := node.child(name); child != nil {
+					fieldValue := value.FieldByIndex(field.index)
+					if fieldValue.CanSet() {
+						bindNode(fieldValue, field.typ, child, label+"."+name, field.layout, explicit, fieldErrors)
+					}
+					break
+				}
+			}
+		}
+	case reflect.Map:
+		if value.IsNil() {
+			value.Set(reflect.MakeMap(typ))
+		}
+		for name, child := range node.children {
+			key := reflect.New(typ.Key()).Elem()
+			if err := setValue(key, typ.Key(), name, ""); err != nil {
+				*fieldErrors = append(*fieldErrors, FieldError{Field: label + "." + name, Rule: "type", Msg: "类型错误"})
+				continue
+			}
+			elem := reflect.New(typ.Elem()).Elem()
+			if old := value.MapIndex(key); old.IsValid() {
+				elem.Set(old)
+			}
+			bindNode(elem, typ.Elem(), child, label+"."+name, layout, explicit, fieldErrors)
+			value.SetMapIndex(key, elem)
+		}
+	case reflect.Slice, reflect.Array:
+		//下标只用来排序, 不按下标分配, 避免 items[100000000] 这样的参数占用大量内存
+		type item struct {
+			index int
+			name  string
+		}
+		var indexes []item
+		for name := range node.children {
+			if index, err := strconv.Atoi(name); err == nil && index >= 0 {
+				indexes = append(indexes, item{index: index, name: name})
+			} else {
+				*fieldErrors = append(*fieldErrors, FieldError{Field: label + "." + name, Rule: "type", Msg: "下标错误"})
+			}
+		}
+		sort.Slice(indexes, func(i, j int) bool {
+			return indexes[i].index < indexes[j].index
+		})
+		if typ.Kind() == reflect.Slice {
+			value.Set(reflect.MakeSlice(typ, len(indexes), len(indexes)))
+		} else if len(indexes) > value.Len() {
+			*fieldErrors = append(*fieldErrors, FieldError{Field: label, Rule: "overflow", Msg: "最多 " + strconv.Itoa(value.Len()) + " 个"})
+			indexes = indexes[:value.Len()]
+		}
+		for i, item := range indexes {
+			bindNode(value.Index(i), typ.Elem(), node.children[item.name], label+"."+item.name, layout, explicit, fieldErrors)
+		}
+	}
+}
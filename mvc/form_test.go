@@ -0,0 +1,84 @@
+package mvc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type formAddress struct {
+	City string
+}
+
+type formUser struct {
+	Name    string
+	Address *formAddress
+}
+
+type formItem struct {
+	Id  int
+	Qty int
+}
+
+type formParam struct {
+	User  formUser
+	Items []formItem
+	Attrs map[string]string
+	Tags  []string
+	Pair  [1]formItem
+}
+
+func (the *testController) Form(param formParam) *JsonView {
+	return JSON(param)
+}
+
+func TestBindNested(t *testing.T) {
+	server := testServer(http.MethodPost, "/form", "Form")
+	body := "user[name]=a&user.address.city=b&items[1][qty]=4&items[0][id]=1&items[0][qty]=2&items[1].id=3&attrs[color]=red&tags[]=x&tags[]=y"
+	req := httptest.NewRequest(http.MethodPost, "/form", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := serve(server, req)
+	var param formParam
+	if err := json.Unmarshal(w.Body.Bytes(), &param); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("status %d, body %s", w.Code, w.Body.String())
+	}
+	want := formParam{
+		User:  formUser{Name: "a", Address: &formAddress{City: "b"}},
+		Items: []formItem{{Id: 1, Qty: 2}, {Id: 3, Qty: 4}},
+		Attrs: map[string]string{"color": "red"},
+		Tags:  []string{"x", "y"},
+	}
+	if !reflect.DeepEqual(param, want) {
+		t.Fatalf("param = %+v, want %+v", param, want)
+	}
+}
+
+// 严格模式下下标错误和数组装不下都报错
+func TestBindNestedStrict(t *testing.T) {
+	server := testServer(http.MethodGet, "/form", "Form")
+	server.StrictBinding = true
+	w := serve(server, httptest.NewRequest(http.MethodGet, "/form?items[x][id]=1&items[0][qty]=a&pair[0][id]=1&pair[1][id]=2", nil))
+	status, fields := validateErrors(w)
+	if status != http.StatusBadRequest || fields != "items.0.qty:type,items.x:type,pair:overflow" {
+		t.Fatalf("status %d, fields %s", status, fields)
+	}
+}
+
+func TestSplitFormKey(t *testing.T) {
+	tests := map[string][]string{
+		"user[name]":        {"user", "name"},
+		"user.address.city": {"user", "address", "city"},
+		"items[1][qty]":     {"items", "1", "qty"},
+		"items[1].id":       {"items", "1", "id"},
+		"tags[]":            {"tags"},
+		"name":              {"name"},
+	}
+	for key, want := range tests {
+		if path := splitFormKey(key); !reflect.DeepEqual(path, want) {
+			t.Errorf("%s: %v, want %v", key, path, want)
+		}
+	}
+}
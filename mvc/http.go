@@ -1,12 +1,14 @@
 package mvc
 
 import (
-	"encoding/json"
+	"bytes"
+	"context"
 	"errors"
 	"github.com/luoshanzhi/monster-go"
 	"io"
 	"mime/multipart"
 	"net/http"
+	"net/url"
 	"os"
 	"reflect"
 	"strconv"
@@ -30,7 +32,41 @@ func commandLineGraceful() string {
 	return graceful
 }
 
+type stateKey struct{}
+
+// 请求处理过程中共享的数据, 放在 req.Context() 里
+type requestState struct {
+	server *Server
+	limits Limits    //Server.Limits 合并路由上的 Limits
+	body   bodyCache //mvc.Body 读取的请求 body
+	ctx    *Context  //注入控制器的 *mvc.Context, 请求结束后放回池里
+}
+
+func stateOf(req *http.Request) *requestState {
+	state, _ := req.Context().Value(stateKey{}).(*requestState)
+	return state
+}
+
+// 取出处理当前请求的 Server, 视图输出时需要用到 Server 上的配置
+func serverOf(req *http.Request) *Server {
+	if state := stateOf(req); state != nil {
+		return state.server
+	}
+	return nil
+}
+
 func routeHandle(server *Server, w http.ResponseWriter, req *http.Request) {
+	state := &requestState{server: server, limits: server.Limits}
+	req = req.WithContext(context.WithValue(req.Context(), stateKey{}, state))
+	//req 是复制出来的, http.Server 不会清理这个 req 上传的临时文件
+	defer func() {
+		if req.MultipartForm != nil {
+			req.MultipartForm.RemoveAll()
+		}
+		if state.ctx != nil {
+			releaseContext(state.ctx)
+		}
+	}()
 	if monster.CurEnv == "release" {
 		defer func() {
 			if err := recover(); err != nil {
@@ -45,132 +81,420 @@ func routeHandle(server *Server, w http.ResponseWriter, req *http.Request) {
 	for i, interceptor := range interceptors {
 		ret := interceptor.Before(w, req, throughoutBefore, i)
 		if ret != nil {
-			fitOut(w, req, ret)
+			fitOut(server, w, req, ret)
 			return
 		}
 	}
 	route, err := server.Handler(req)
 	if err != nil {
-		ResponseOut(w, http.StatusInternalServerError, nil, err.Error())
+		var methodErr *MethodNotAllowedError
+		if errors.Is(err, ErrNotFound) {
+			ResponseOut(w, http.StatusNotFound, nil, err.Error())
+		} else if errors.As(err, &methodErr) {
+			mMap := make(map[string]bool)
+			for _, method := range methodErr.Allow {
+				mMap[method] = true
+			}
+			methodOut(w, req, mMap)
+		} else {
+			ResponseOut(w, http.StatusInternalServerError, nil, err.Error())
+		}
+		return
+	}
+	state.limits = mergeLimits(server.Limits, route.Limits)
+	limitBody(w, req, state.limits)
+	if route.Handler != nil {
+		route.Handler.ServeHTTP(w, req)
 		return
 	}
 	controllerName := route.ControllerName
 	if !monster.In(controllerName) {
-		ResponseOut(w, http.StatusInternalServerError, nil, "错误的路由")
+		ResponseOut(w, http.StatusNotFound, nil, "错误的路由")
 		return
 	}
 	methodName := route.MethodName
 	controller := monster.Factory(controllerName)
 	if controller == nil {
-		ResponseOut(w, http.StatusInternalServerError, nil, "错误的路由")
+		ResponseOut(w, http.StatusNotFound, nil, "错误的路由")
 		return
 	}
-	if methodName == "Init" || methodName == "Use" {
-		ResponseOut(w, http.StatusInternalServerError, nil, "错误的路由函数")
+	if methodName == "Init" || methodName == "Use" || methodName == "Multiton" {
+		ResponseOut(w, http.StatusNotFound, nil, "错误的路由函数")
 		return
 	}
-	controllerType := reflect.TypeOf(controller)
-	if _, ok := controllerType.MethodByName(methodName); !ok {
-		ResponseOut(w, http.StatusInternalServerError, nil, "错误的路由函数")
+	controllerValue := reflect.ValueOf(controller)
+	plan := getMethodPlan(controllerValue.Type(), methodName)
+	if plan == nil {
+		ResponseOut(w, http.StatusNotFound, nil, "错误的路由函数")
 		return
 	}
-	controllerValue := reflect.ValueOf(controller)
-	controllerFunc := controllerValue.MethodByName(methodName)
-	controllerFuncType := controllerFunc.Type()
-	if !vaildMethod(req, controllerFuncType) {
-		ResponseOut(w, http.StatusInternalServerError, nil, "错误的路由请求类型")
+	controllerFunc := controllerValue.Method(plan.index)
+	//OPTIONS 请求除非控制器方法标记了 mvc.OPTIONS, 否则框架直接输出 Allow
+	if strings.ToUpper(req.Method) == http.MethodOptions && !plan.allow["OPTIONS"] {
+		methodOut(w, req, plan.allow)
 		return
 	}
-	paramNum := controllerFuncType.NumIn()
-	paramList := make([]reflect.Value, paramNum)
-	wType := reflect.TypeOf(w)
-	reqType := reflect.TypeOf(req)
-	for i := 0; i < paramNum; i++ {
-		in := controllerFuncType.In(i)
-		if wType.AssignableTo(in) {
-			paramList[i] = reflect.ValueOf(w)
-		} else if reqType.AssignableTo(in) {
-			paramList[i] = reflect.ValueOf(req)
-		} else if in.Kind() == reflect.Struct {
-			paramList[i] = parseParam(req, in)
-		} else {
-			//不写这句会报"reflect: Call using zero Value argument",用在 _ mvc.GET 等传参
-			paramList[i] = reflect.New(in).Elem()
+	if !vaildMethod(req, plan.allow) {
+		methodOut(w, req, plan.allow)
+		return
+	}
+	paramList := make([]reflect.Value, len(plan.binders))
+	for i, bind := range plan.binders {
+		param, bindErr := bind(server, w, req, route)
+		if bindErr != nil {
+			errorOut(server, w, req, bindErr)
+			return
 		}
+		paramList[i] = param
 	}
 	var throughoutInvoke = make([]reflect.Value, interceptorsLength)
 	for i, interceptor := range interceptors {
 		ret := interceptor.Invoke(w, req, controllerFunc, paramList, throughoutInvoke, i)
 		if ret != nil {
-			fitOut(w, req, ret)
+			fitOut(server, w, req, ret)
 			return
 		}
 	}
+	if plan.outErr != "" {
+		ResponseOut(w, http.StatusInternalServerError, nil, plan.outErr)
+		return
+	}
 	returns := controllerFunc.Call(paramList)
-	returnLength := len(returns)
-	if returnLength == 1 {
+	//支持 View, (View, error), error 三种返回
+	if plan.errIndex != -1 && !returns[plan.errIndex].IsNil() {
+		errorOut(server, w, req, returns[plan.errIndex].Interface().(error))
+		return
+	}
+	if plan.viewIndex != -1 {
 		var throughoutAfter = make([]reflect.Value, interceptorsLength)
 		for i, interceptor := range interceptors {
-			ret := interceptor.After(w, req, returns[0], throughoutAfter, i)
+			ret := interceptor.After(w, req, returns[plan.viewIndex], throughoutAfter, i)
 			if ret != nil {
-				fitOut(w, req, ret)
+				fitOut(server, w, req, ret)
 				return
 			}
 		}
-		fitOut(w, req, returns[0].Interface())
-	} else if returnLength > 1 {
-		ResponseOut(w, http.StatusInternalServerError, nil, "路由函数只能返回1个返回值")
+		fitOut(server, w, req, returns[plan.viewIndex].Interface())
 	}
 }
 
-func vaildMethod(req *http.Request, funcType reflect.Type) bool {
+func vaildMethod(req *http.Request, mMap map[string]bool) bool {
 	method := strings.ToUpper(req.Method)
-	mMap := make(map[string]bool)
-	for i, paramNum := 0, funcType.NumIn(); i < paramNum; i++ {
-		in := funcType.In(i)
-		if reflect.TypeOf((*OPTIONS)(nil)).Elem() == in {
-			mMap["OPTIONS"] = true
-		} else if reflect.TypeOf((*GET)(nil)).Elem() == in {
-			mMap["GET"] = true
-		} else if reflect.TypeOf((*HEAD)(nil)).Elem() == in {
-			mMap["HEAD"] = true
-		} else if reflect.TypeOf((*POST)(nil)).Elem() == in {
-			mMap["POST"] = true
-		} else if reflect.TypeOf((*PUT)(nil)).Elem() == in {
-			mMap["PUT"] = true
-		} else if reflect.TypeOf((*DELETE)(nil)).Elem() == in {
-			mMap["DELETE"] = true
-		} else if reflect.TypeOf((*TRACE)(nil)).Elem() == in {
-			mMap["TRACE"] = true
-		} else if reflect.TypeOf((*CONNECT)(nil)).Elem() == in {
-			mMap["CONNECT"] = true
-		}
-	}
 	if len(mMap) == 0 {
 		return true
 	} else {
 		_, ok := mMap[method]
+		//HEAD 请求按 GET 处理, http.Server 不会输出 body
+		if !ok && method == http.MethodHead {
+			_, ok = mMap[http.MethodGet]
+		}
 		return ok
 	}
 }
 
-func parseParam(req *http.Request, in reflect.Type) reflect.Value {
+// OPTIONS 请求输出 204, 其他请求输出 405, 都带 Allow 头
+func methodOut(w http.ResponseWriter, req *http.Request, mMap map[string]bool) {
+	header := map[string]string{
+		"Allow": allowHeader(mMap),
+	}
+	if strings.ToUpper(req.Method) == http.MethodOptions {
+		ResponseOut(w, http.StatusNoContent, header, "")
+	} else {
+		ResponseOut(w, http.StatusMethodNotAllowed, header, "错误的路由请求类型")
+	}
+}
+
+// 按参数里的 mvc.GET, mvc.POST 等标记取出允许的请求方法, 没有标记代表允许所有
+func allowMethods(funcType reflect.Type) map[string]bool {
+	mMap := make(map[string]bool)
+	for i, paramNum := 0, funcType.NumIn(); i < paramNum; i++ {
+		if method, ok := markerTypes[funcType.In(i)]; ok {
+			mMap[method] = true
+		}
+	}
+	return mMap
+}
+
+// 参数取值: 标签指定来源(path, query, form, header, cookie)就只从该来源取,
+// 否则按 json 名字, 首字母小写, 字段名依次从 post 区和 get 区取, Server.ExplicitBinding 时只认 json 名字
+// 取不到值时用 default 标签的值, 最后按 validate 标签校验
+// 严格模式(Server.StrictBinding 或参数结构实现 mvc.Strict)下类型转换失败, json 格式错误, json 多余字段, 数组装不下都输出 400
+func parseParam(server *Server, req *http.Request, route Route, in reflect.Type) (reflect.Value, error) {
+	plan := getParamPlan(in)
+	if plan.err != nil {
+		return reflect.Value{}, plan.err
+	}
 	objAddr := reflect.New(in)
 	objValue := objAddr.Elem()
-	if parseJson(req, objAddr.Interface()) {
-		return objValue
-	}
-	objType := objValue.Type()
-	numField := objType.NumField()
-	req.ParseForm()
-	get := req.URL.Query() //get区取数据
-	post := req.PostForm   //post区取数据
-	file := parseFile(req) //post区上传取数据
-	setValue := func(value reflect.Value, type_ reflect.Type, item interface{}) {
-		newValue := value
-		if !isFile(type_) && type_.Kind() == reflect.Ptr {
-			newValue = reflect.New(type_.Elem()).Elem()
+	newEmbeds(plan, objValue)
+	//先填默认值, json 里没有的字段保留默认值
+	for i := range plan.fields {
+		field := &plan.fields[i]
+		if field.hasDefault && !field.isFile && !field.nested {
+			setList(objValue.FieldByIndex(field.index), field.typ, defaultList(field), field.layout)
+		}
+	}
+	strict := plan.strict || (server != nil && server.StrictBinding)
+	var fieldErrors, nestedErrors []FieldError
+	//json, xml 等提交时 body 里的字段由 Decoder 解析, 只从其他来源补充指定了来源的字段
+	bodyOk, bodyErr := parseBody(server, req, objAddr.Interface(), strict)
+	if bodyErr != nil {
+		//读取失败(比如超过 Limits.MaxBodySize)总是报错, 解析失败只有严格模式报错
+		var readErr *Error
+		if errors.As(bodyErr, &readErr) {
+			return reflect.Value{}, bodyErr
+		}
+		if strict {
+			var validationErr *ValidationError
+			if errors.As(bodyErr, &validationErr) {
+				return reflect.Value{}, bodyErr
+			}
+			return reflect.Value{}, &ValidationError{Errors: []FieldError{decodeFieldError(bodyErr)}}
+		}
+	}
+	//指定了来源的字段只从该来源取值, 不能被 body 里同名的字段伪造
+	if bodyOk || bodyErr != nil {
+		for i := range plan.fields {
+			field := &plan.fields[i]
+			if field.source == "" {
+				continue
+			}
+			fieldValue := objValue.FieldByIndex(field.index)
+			fieldValue.Set(reflect.Zero(field.typ))
+			if field.hasDefault && !field.isFile && !field.nested {
+				setList(fieldValue, field.typ, defaultList(field), field.layout)
+			}
+		}
+	}
+	var get, post url.Values
+	var file map[string][]*multipart.FileHeader
+	//body 已经被 mvc.Body 读过, 重新放回去给 ParseForm 读
+	if state := stateOf(req); state != nil && state.body.read {
+		Body(req)
+	}
+	if err := req.ParseForm(); err != nil && StatusOf(err) == http.StatusRequestEntityTooLarge {
+		return reflect.Value{}, bodyError(err)
+	}
+	get = req.URL.Query() //get区取数据
+	if !bodyOk {
+		post = req.PostForm //post区取数据
+		var fileErr error
+		if file, fileErr = parseFile(req); fileErr != nil { //post区上传取数据
+			return reflect.Value{}, fileErr
+		}
+	}
+	explicit := server != nil && server.ExplicitBinding
+	//嵌套参数用到时才解析
+	var queryForm, postForm, allForm *formNode
+	for i := range plan.fields {
+		field := &plan.fields[i]
+		if field.nested && !(bodyOk && field.source == "") {
+			var node *formNode
+			names := field.names
+			switch field.source {
+			case "query":
+				if queryForm == nil {
+					queryForm = buildForm([]url.Values{get}, nil)
+				}
+				node, names = queryForm, []string{field.key}
+			case "form":
+				if postForm == nil {
+					postForm = buildForm([]url.Values{post}, file)
+				}
+				node, names = postForm, []string{field.key}
+			case "":
+				if allForm == nil {
+					allForm = buildForm([]url.Values{get, post}, file)
+				}
+				node = allForm
+				if explicit {
+					names = nil
+					if field.jsonName != "" {
+						names = []string{field.jsonName}
+					}
+				}
+			}
+			for _, name := range names {
+				if child := node.child(name); child != nil {
+					bindNode(objValue.FieldByIndex(field.index), field.typ, child, name, field.layout, explicit, &nestedErrors)
+					break
+				}
+			}
+			continue
+		}
+		var list []interface{}
+		switch field.source {
+		case "path":
+			//路径参数, 如 /users/{id} 对应 `path:"id"`
+			if val, ok := route.Params[field.key]; ok {
+				list = append(list, val)
+			}
+		case "query":
+			list = stringList(get[field.key])
+		case "form":
+			if field.isFile {
+				list = fileList(file[field.key])
+			} else {
+				list = stringList(post[field.key])
+			}
+		case "header":
+			list = stringList(req.Header.Values(field.key))
+		case "cookie":
+			if cookie, err := req.Cookie(field.key); err == nil {
+				list = append(list, cookie.Value)
+			}
+		default:
+			if bodyOk {
+				continue
+			}
+			names := field.names
+			if explicit {
+				names = nil
+				if field.jsonName != "" {
+					names = []string{field.jsonName}
+				}
+			}
+			for _, name := range names {
+				if list = formValues(name, field.isFile, post, get, file); len(list) > 0 {
+					break
+				}
+			}
+			//tags[]=a&tags[]=b
+			if len(list) == 0 && field.isList {
+				for _, name := range names {
+					if list = formValues(name+"[]", field.isFile, post, get, file); len(list) > 0 {
+						break
+					}
+				}
+			}
+		}
+		if len(list) == 0 {
+			continue
+		}
+		if err := setList(objValue.FieldByIndex(field.index), field.typ, list, field.layout); err != nil && strict {
+			fieldErrors = append(fieldErrors, bindFieldError(field, err))
+		}
+	}
+	if strict {
+		fieldErrors = append(fieldErrors, nestedErrors...)
+	}
+	//取值出错时不再校验, 避免同一个字段报两次错
+	if len(fieldErrors) > 0 {
+		return reflect.Value{}, &ValidationError{Errors: fieldErrors}
+	}
+	if err := validateParam(plan, objValue); err != nil {
+		return reflect.Value{}, err
+	}
+	return objValue, nil
+}
+
+// 按参数名取值, post 区优先于 get 区
+func formValues(name string, isFile bool, post url.Values, get url.Values, file map[string][]*multipart.FileHeader) []interface{} {
+	if isFile {
+		return fileList(file[name])
+	} else if vals, ok := post[name]; ok && len(vals) > 0 {
+		return stringList(vals)
+	}
+	return stringList(get[name])
+}
+
+// 内嵌匿名字段如果是引用就创建新对象
+func newEmbeds(plan *paramPlan, objValue reflect.Value) {
+	for _, index := range plan.embeds {
+		field := objValue.FieldByIndex(index)
+		unsafeFieldValue := reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem()
+		if unsafeFieldValue.IsNil() {
+			unsafeFieldValue.Set(reflect.New(field.Type().Elem()))
+		}
+	}
+}
+
+func stringList(vals []string) []interface{} {
+	list := make([]interface{}, 0, len(vals))
+	for _, v := range vals {
+		list = append(list, v)
+	}
+	return list
+}
+
+func fileList(files []*multipart.FileHeader) []interface{} {
+	list := make([]interface{}, 0, len(files))
+	for _, f := range files {
+		list = append(list, f)
+	}
+	return list
+}
+
+// 默认值, 切片和数组用逗号分隔多个值
+func defaultList(field *paramField) []interface{} {
+	valueType := field.typ
+	if valueType.Kind() == reflect.Ptr {
+		valueType = valueType.Elem()
+	}
+	if valueType.Kind() == reflect.Slice || valueType.Kind() == reflect.Array {
+		return stringList(strings.Split(field.defaultVal, ","))
+	}
+	return []interface{}{field.defaultVal}
+}
+
+// 返回第一个转换错误, 数组装不下时返回 errOverflow, layout 是 time.Time 的格式
+func setList(valueField reflect.Value, fieldType reflect.Type, list []interface{}, layout string) error {
+	var err error
+	valueType := fieldType
+	if valueType.Kind() == reflect.Ptr {
+		valueType = valueType.Elem()
+	}
+	switch valueType.Kind() {
+	case reflect.Slice, reflect.Array:
+		if isConvertible(valueType) {
+			err = setValue(valueField, fieldType, list[0], layout)
+			break
+		}
+		valueItemType := valueType.Elem()
+		listValue := reflect.New(valueType).Elem()
+		listLen := listValue.Len()
+		for i, item := range list {
+			newValue := reflect.New(valueItemType).Elem()
+			if setErr := setValue(newValue, valueItemType, item, layout); setErr != nil && err == nil {
+				err = setErr
+			}
+			if valueType.Kind() == reflect.Slice {
+				listValue.Set(reflect.Append(listValue, newValue))
+			} else if valueType.Kind() == reflect.Array {
+				//如果是数组类型接收,参数可能超出数组长度
+				if i <= listLen-1 {
+					listValue.Index(i).Set(newValue)
+				} else if err == nil {
+					err = errOverflow
+				}
+			}
 		}
+		if valueField.Kind() == reflect.Ptr {
+			valueField.Set(listValue.Addr())
+		} else {
+			valueField.Set(listValue)
+		}
+	default:
+		err = setValue(valueField, fieldType, list[0], layout)
+	}
+	return err
+}
+
+// 转换失败时返回 error, 字段保持零值, 严格模式下才会报错
+// 先按 RegisterConverter 注册的转换, 再按 time.Time(layout 标签), time.Duration, encoding.TextUnmarshaler, json.Unmarshaler, 最后按基础类型
+func setValue(value reflect.Value, type_ reflect.Type, item interface{}, layout string) error {
+	var convErr error
+	newValue := value
+	if !isFile(type_) && type_.Kind() == reflect.Ptr {
+		newValue = reflect.New(type_.Elem()).Elem()
+	}
+	str, isStr := item.(string)
+	if !isStr {
+		//上传文件等
+		convErr = assignItem(newValue, item)
+	} else if ok, err := convertSpecial(newValue, strings.TrimSpace(str), layout); ok {
+		convErr = err
+	} else {
 		switch newValue.Kind() {
 		case reflect.String:
 			if val, ok := item.(string); ok {
@@ -179,84 +503,108 @@ func parseParam(req *http.Request, in reflect.Type) reflect.Value {
 		case reflect.Int:
 			if val, ok := item.(string); ok {
 				val = strings.TrimSpace(val)
-				if val, err := strconv.ParseInt(val, 10, 64); err == nil {
+				if val, err := strconv.ParseInt(val, 10, 64); err != nil {
+					convErr = err
+				} else {
 					newValue.Set(reflect.ValueOf(int(val)))
 				}
 			}
 		case reflect.Int64:
 			if val, ok := item.(string); ok {
 				val = strings.TrimSpace(val)
-				if val, err := strconv.ParseInt(val, 10, 64); err == nil {
+				if val, err := strconv.ParseInt(val, 10, 64); err != nil {
+					convErr = err
+				} else {
 					newValue.Set(reflect.ValueOf(val))
 				}
 			}
 		case reflect.Int32:
 			if val, ok := item.(string); ok {
 				val = strings.TrimSpace(val)
-				if val, err := strconv.ParseInt(val, 10, 32); err == nil {
+				if val, err := strconv.ParseInt(val, 10, 32); err != nil {
+					convErr = err
+				} else {
 					newValue.Set(reflect.ValueOf(int32(val)))
 				}
 			}
 		case reflect.Int16:
 			if val, ok := item.(string); ok {
 				val = strings.TrimSpace(val)
-				if val, err := strconv.ParseInt(val, 10, 16); err == nil {
+				if val, err := strconv.ParseInt(val, 10, 16); err != nil {
+					convErr = err
+				} else {
 					newValue.Set(reflect.ValueOf(int16(val)))
 				}
 			}
 		case reflect.Int8:
 			if val, ok := item.(string); ok {
 				val = strings.TrimSpace(val)
-				if val, err := strconv.ParseInt(val, 10, 8); err == nil {
+				if val, err := strconv.ParseInt(val, 10, 8); err != nil {
+					convErr = err
+				} else {
 					newValue.Set(reflect.ValueOf(int8(val)))
 				}
 			}
 		case reflect.Uint:
 			if val, ok := item.(string); ok {
 				val = strings.TrimSpace(val)
-				if val, err := strconv.ParseUint(val, 10, 64); err == nil {
+				if val, err := strconv.ParseUint(val, 10, 64); err != nil {
+					convErr = err
+				} else {
 					newValue.Set(reflect.ValueOf(uint(val)))
 				}
 			}
 		case reflect.Uint64:
 			if val, ok := item.(string); ok {
 				val = strings.TrimSpace(val)
-				if val, err := strconv.ParseUint(val, 10, 64); err == nil {
+				if val, err := strconv.ParseUint(val, 10, 64); err != nil {
+					convErr = err
+				} else {
 					newValue.Set(reflect.ValueOf(val))
 				}
 			}
 		case reflect.Uint32:
 			if val, ok := item.(string); ok {
 				val = strings.TrimSpace(val)
-				if val, err := strconv.ParseUint(val, 10, 32); err == nil {
+				if val, err := strconv.ParseUint(val, 10, 32); err != nil {
+					convErr = err
+				} else {
 					newValue.Set(reflect.ValueOf(uint32(val)))
 				}
 			}
 		case reflect.Uint16:
 			if val, ok := item.(string); ok {
 				val = strings.TrimSpace(val)
-				if val, err := strconv.ParseUint(val, 10, 16); err == nil {
+				if val, err := strconv.ParseUint(val, 10, 16); err != nil {
+					convErr = err
+				} else {
 					newValue.Set(reflect.ValueOf(uint16(val)))
 				}
 			}
 		case reflect.Uint8:
 			if val, ok := item.(string); ok {
 				val = strings.TrimSpace(val)
-				if val, err := strconv.ParseUint(val, 10, 8); err == nil {
+				if val, err := strconv.ParseUint(val, 10, 8); err != nil {
+					convErr = err
+				} else {
 					newValue.Set(reflect.ValueOf(uint8(val)))
 				}
 			}
 		case reflect.Float64:
 			if val, ok := item.(string); ok {
 				val = strings.TrimSpace(val)
-				if val, err := strconv.ParseFloat(val, 64); err == nil {
+				if val, err := strconv.ParseFloat(val, 64); err != nil {
+					convErr = err
+				} else {
 					newValue.Set(reflect.ValueOf(val))
 				}
 			}
 		case reflect.Float32:
 			if val, ok := item.(string); ok {
 				val = strings.TrimSpace(val)
-				if val, err := strconv.ParseFloat(val, 32); err == nil {
+				if val, err := strconv.ParseFloat(val, 32); err != nil {
+					convErr = err
+				} else {
 					newValue.Set(reflect.ValueOf(float32(val)))
 				}
 			}
@@ -268,138 +616,70 @@ func parseParam(req *http.Request, in reflect.Type) reflect.Value {
 					newVal = false
 				} else if val == "true" {
 					newVal = true
+				} else {
+					convErr = errors.New("not bool: " + val)
 				}
 				newValue.Set(reflect.ValueOf(newVal))
 			}
 		case reflect.Complex128:
 			if val, ok := item.(string); ok {
 				val = strings.TrimSpace(val)
-				if val, err := strconv.ParseComplex(val, 128); err == nil {
+				if val, err := strconv.ParseComplex(val, 128); err != nil {
+					convErr = err
+				} else {
 					newValue.Set(reflect.ValueOf(val))
 				}
 			}
 		case reflect.Complex64:
 			if val, ok := item.(string); ok {
 				val = strings.TrimSpace(val)
-				if val, err := strconv.ParseComplex(val, 64); err == nil {
+				if val, err := strconv.ParseComplex(val, 64); err != nil {
+					convErr = err
+				} else {
 					newValue.Set(reflect.ValueOf(complex64(val)))
 				}
 			}
 		default:
-			newValue.Set(reflect.ValueOf(item))
-		}
-		if !isFile(value.Type()) && value.Kind() == reflect.Ptr {
-			value.Set(newValue.Addr())
-		} else {
-			value.Set(newValue)
+			//不支持的类型不再 panic, 当作转换失败
+			convErr = errors.New("unsupported type " + newValue.Type().String())
 		}
 	}
-	listFunc := func(field reflect.StructField, fieldName string) []interface{} {
-		var list []interface{}
-		if isFile(field.Type) {
-			if files, ok := file[fieldName]; ok && len(files) > 0 {
-				for _, f := range files {
-					list = append(list, f)
-				}
-			}
-		} else {
-			if vals, ok := post[fieldName]; ok && len(vals) > 0 {
-				for _, v := range vals {
-					list = append(list, v)
-				}
-			} else if vals, ok := get[fieldName]; ok && len(vals) > 0 {
-				for _, v := range vals {
-					list = append(list, v)
-				}
-			}
-		}
-		return list
-	}
-	//获取所有字段, 如果存在匿名字段就遍历, 如果是引用就创建新对象
-	var fields []reflect.StructField
-	var fieldsFun func(field reflect.Value, structField reflect.StructField)
-	fieldsFun = func(field reflect.Value, structField reflect.StructField) {
-		sfType := structField.Type
-		if structField.Anonymous {
-			if sfType.Kind() == reflect.Ptr {
-				unsafeFieldValue := reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem()
-				unsafeFieldValue.Set(reflect.New(field.Type().Elem()))
-				field = unsafeFieldValue.Elem()
-				sfType = sfType.Elem()
-			}
-			if sfType.Kind() == reflect.Struct {
-				numField := sfType.NumField()
-				for i := 0; i < numField; i++ {
-					fieldsFun(field.Field(i), sfType.Field(i))
-				}
-			}
-		} else {
-			fields = append(fields, structField)
-		}
+	if !isFile(value.Type()) && value.Kind() == reflect.Ptr {
+		value.Set(newValue.Addr())
+	} else {
+		value.Set(newValue)
 	}
-	//遍历字段, 包括内嵌匿名字段
-	for i := 0; i < numField; i++ {
-		fieldsFun(objValue.Field(i), objType.Field(i))
+	//空字符串当作没有传值
+	if isStr && strings.TrimSpace(str) == "" {
+		return nil
 	}
-	for _, field := range fields {
-		fieldName := field.Name
-		valueField := objValue.FieldByName(fieldName)
-		//参数首字母小写
-		list := listFunc(field, monster.FirstLower(fieldName))
-		if len(list) == 0 {
-			list = listFunc(field, fieldName)
-			if len(list) == 0 {
-				continue
-			}
-		}
-		valueType := field.Type
-		if valueType.Kind() == reflect.Ptr {
-			valueType = valueType.Elem()
-		}
-		switch valueType.Kind() {
-		case reflect.Slice, reflect.Array:
-			valueItemType := valueType.Elem()
-			listValue := reflect.New(valueType).Elem()
-			listLen := listValue.Len()
-			for i, item := range list {
-				newValue := reflect.New(valueItemType).Elem()
-				setValue(newValue, valueItemType, item)
-				if valueType.Kind() == reflect.Slice {
-					listValue.Set(reflect.Append(listValue, newValue))
-				} else if valueType.Kind() == reflect.Array {
-					//如果是数组类型接收,参数可能超出数组长度
-					if i <= listLen-1 {
-						listValue.Index(i).Set(newValue)
-					}
-				}
-			}
-			if valueField.Kind() == reflect.Ptr {
-				valueField.Set(listValue.Addr())
-			} else {
-				valueField.Set(listValue)
-			}
-		default:
-			setValue(valueField, field.Type, list[0])
-		}
-	}
-	return objValue
+	return convErr
 }
 
 func isFile(type_ reflect.Type) bool {
 	return strings.Index(type_.String(), "*multipart.FileHeader") != -1
 }
 
-func parseFile(req *http.Request) map[string][]*multipart.FileHeader {
+// 按 Limits 解析上传, 超过限制返回 413 错误
+func parseFile(req *http.Request) (map[string][]*multipart.FileHeader, error) {
 	file := make(map[string][]*multipart.FileHeader)
 	contentType := strings.ToLower(req.Header.Get("Content-Type"))
 	if strings.Index(contentType, "multipart/form-data") == -1 {
-		return file
+		return file, nil
+	}
+	var limits Limits
+	if state := stateOf(req); state != nil {
+		limits = state.limits
 	}
-	//http默认上传内存
-	var defaultMaxMemory int64 = 32 << 20 // 32 MB
-	err := req.ParseMultipartForm(defaultMaxMemory)
+	err := req.ParseMultipartForm(limits.maxMultipartMemory())
 	if err != nil {
-		return file
+		if StatusOf(err) == http.StatusRequestEntityTooLarge {
+			return file, bodyError(err)
+		}
+		return file, nil
+	}
+	if err := checkFiles(req, limits); err != nil {
+		return file, err
 	}
 	if req.MultipartForm != nil && req.MultipartForm.File != nil {
 		for key, fhs := range req.MultipartForm.File {
@@ -413,30 +693,58 @@ func parseFile(req *http.Request) map[string][]*multipart.FileHeader {
 			}
 		}
 	}
-	return file
-}
-
-func parseJson(req *http.Request, obj interface{}) bool {
-	contentType := strings.ToLower(req.Header.Get("Content-Type"))
-	if strings.Index(contentType, "application/json") == -1 {
-		return false
-	}
-	body, err := io.ReadAll(req.Body)
-	if err != nil {
-		return false
-	}
-	jsonErr := json.Unmarshal(body, obj)
-	return jsonErr == nil
+	return file, nil
 }
 
-func fitOut(w http.ResponseWriter, req *http.Request, val interface{}) {
+// 返回值是 View 就调用 Out, 否则按类型输出: string 文本, []byte 二进制, io.Reader 流, 其他按 Accept 或 Server.DefaultContentType 序列化
+func fitOut(server *Server, w http.ResponseWriter, req *http.Request, val interface{}) {
 	var err error
-	if view, ok := val.(View); ok {
-		if outErr := view.Out(w, req); outErr != nil {
+	//(*Json, error) 这样的方法 return nil, nil 时 val 是有类型的 nil 指针, 和 nil 一样输出 204
+	if value := reflect.ValueOf(val); value.Kind() == reflect.Ptr && value.IsNil() {
+		val = nil
+	}
+	switch obj := val.(type) {
+	case nil:
+		w.WriteHeader(http.StatusNoContent)
+	case View:
+		if outErr := obj.Out(w, req); outErr != nil {
+			monster.ErrorLog.Error(req.URL.Path+":", outErr)
 			err = errors.New("路由函数输出错误")
 		}
-	} else {
-		err = errors.New("错误的路由函数输出")
+	case string:
+		ResponseOut(w, http.StatusOK, map[string]string{"Content-Type": "text/plain; charset=utf-8"}, obj)
+	case []byte:
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.WriteHeader(http.StatusOK)
+		w.Write(obj)
+	case io.Reader:
+		if closer, ok := obj.(io.Closer); ok {
+			defer closer.Close()
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.WriteHeader(http.StatusOK)
+		io.Copy(w, obj)
+	default:
+		contentType, serializer := negotiate(server, req)
+		buf := new(bytes.Buffer)
+		//先序列化到缓存, 出错时还能输出 500
+		serializeErr := serializer.Serialize(buf, val)
+		//比如结构里有 map 字段时 xml 不支持, 换成默认的序列化方式
+		if serializeErr != nil {
+			if defaultType, fallback := defaultSerializer(server); defaultType != contentType {
+				buf.Reset()
+				contentType = defaultType
+				serializeErr = fallback.Serialize(buf, val)
+			}
+		}
+		if serializeErr != nil {
+			monster.ErrorLog.Error(req.URL.Path+":", serializeErr)
+			err = errors.New("路由函数输出错误")
+			break
+		}
+		w.Header().Set("Content-Type", contentType+"; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write(buf.Bytes())
 	}
 	if err != nil {
 		ResponseOut(w, http.StatusInternalServerError, nil, err.Error())
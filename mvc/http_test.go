@@ -0,0 +1,48 @@
+package mvc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func (the *testController) NilView() (*JsonView, error) {
+	return nil, nil
+}
+
+func (the *testController) NotFound() (*JsonView, error) {
+	return nil, NotFound("不存在")
+}
+
+func TestNilView(t *testing.T) {
+	server := testServer(http.MethodGet, "/nil", "NilView")
+	w := serve(server, httptest.NewRequest(http.MethodGet, "/nil", nil))
+	if w.Code != http.StatusNoContent || w.Body.Len() != 0 {
+		t.Fatalf("status %d, body %s", w.Code, w.Body.String())
+	}
+}
+
+func TestErrorView(t *testing.T) {
+	server := testServer(http.MethodGet, "/error", "NotFound")
+	w := serve(server, httptest.NewRequest(http.MethodGet, "/error", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("status %d, body %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAllowHeader(t *testing.T) {
+	tests := []struct {
+		mMap map[string]bool
+		want string
+	}{
+		{nil, "DELETE, GET, HEAD, OPTIONS, PATCH, POST, PUT"},
+		{map[string]bool{"GET": true}, "GET, HEAD, OPTIONS"},
+		{map[string]bool{"POST": true, "PUT": true}, "OPTIONS, POST, PUT"},
+		{map[string]bool{"OPTIONS": true, "HEAD": true}, "HEAD, OPTIONS"},
+	}
+	for _, test := range tests {
+		if got := allowHeader(test.mMap); got != test.want {
+			t.Errorf("%v: got %s, want %s", test.mMap, got, test.want)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package mvc
 
 import (
+	"io"
 	"net/http"
 	"reflect"
 )
@@ -14,6 +15,17 @@ type DELETE interface{}
 type TRACE interface{}
 type CONNECT interface{}
 
+// Decoder 按 Content-Type 把请求 body 解析到参数结构, 用 Server.Decoders 或 RegisterDecoder 注册
+// strict 为 true 时是严格模式, 能判断多余字段的格式应该报错
+type Decoder interface {
+	Decode(body []byte, obj interface{}, strict bool) error
+}
+
+// Strict 参数结构实现这个接口就按严格模式取值, 类型转换失败等错误输出 400, 和 Server.StrictBinding 效果一样
+type Strict interface {
+	Strict()
+}
+
 type Interceptor interface {
 	Before(w http.ResponseWriter, req *http.Request, throughout []reflect.Value, throughoutIndex int) View
 	Invoke(w http.ResponseWriter, req *http.Request, method reflect.Value, in []reflect.Value, throughout []reflect.Value, throughoutIndex int) View
@@ -22,3 +34,8 @@ type Interceptor interface {
 type View interface {
 	Out(http.ResponseWriter, *http.Request) error
 }
+
+// Serializer 控制器返回普通值时的序列化方式, 用 RegisterSerializer 按 Content-Type 注册
+type Serializer interface {
+	Serialize(w io.Writer, val interface{}) error
+}
@@ -0,0 +1,93 @@
+package mvc
+
+import (
+	"errors"
+	"net/http"
+)
+
+const (
+	defaultMaxBodySize        int64 = 32 << 20 // 32 MB
+	defaultMaxMultipartMemory int64 = 32 << 20 // 32 MB
+)
+
+// Limits 请求大小限制, 0 代表默认值, 小于 0 代表不限制
+// Server.Limits 对所有路由生效, RouteEntry.Limits 只对一条路由生效, 路由上不为 0 的配置覆盖 Server 上的
+// 超过限制输出 413
+// 前置拦截器里调用 mvc.Body 时还没有路由, 只按 Server.Limits 限制, 路由上放大的 MaxBodySize 对这个请求不再生效
+// 上传文件和断点续传的 body 不受影响, mvc.Body 不读它们, 见 ErrStreamBody
+type Limits struct {
+	MaxBodySize        int64 //请求 body 最大字节数, 默认 32MB, 上传文件也算在里面
+	MaxMultipartMemory int64 //上传内容超过这个大小就写到临时文件, 默认 32MB
+	MaxFiles           int   //最多上传几个文件, 默认不限制
+	MaxFileSize        int64 //单个上传文件最大字节数, 默认不限制
+}
+
+// 路由上的配置覆盖 Server 上的
+func mergeLimits(server Limits, route *Limits) Limits {
+	limits := server
+	if route != nil {
+		if route.MaxBodySize != 0 {
+			limits.MaxBodySize = route.MaxBodySize
+		}
+		if route.MaxMultipartMemory != 0 {
+			limits.MaxMultipartMemory = route.MaxMultipartMemory
+		}
+		if route.MaxFiles != 0 {
+			limits.MaxFiles = route.MaxFiles
+		}
+		if route.MaxFileSize != 0 {
+			limits.MaxFileSize = route.MaxFileSize
+		}
+	}
+	return limits
+}
+
+func (the Limits) maxBodySize() int64 {
+	if the.MaxBodySize == 0 {
+		return defaultMaxBodySize
+	}
+	return the.MaxBodySize
+}
+
+func (the Limits) maxMultipartMemory() int64 {
+	if the.MaxMultipartMemory <= 0 {
+		return defaultMaxMultipartMemory
+	}
+	return the.MaxMultipartMemory
+}
+
+// 用 http.MaxBytesReader 限制请求 body, 超过时读取会返回 *http.MaxBytesError
+func limitBody(w http.ResponseWriter, req *http.Request, limits Limits) {
+	if size := limits.maxBodySize(); size > 0 && req.Body != nil && req.Body != http.NoBody {
+		req.Body = http.MaxBytesReader(w, req.Body, size)
+	}
+}
+
+// 检查上传文件个数和大小
+func checkFiles(req *http.Request, limits Limits) error {
+	if req.MultipartForm == nil {
+		return nil
+	}
+	count := 0
+	for _, fileHeaders := range req.MultipartForm.File {
+		for _, fileHeader := range fileHeaders {
+			count++
+			if limits.MaxFileSize > 0 && fileHeader.Size > limits.MaxFileSize {
+				return TooLarge("上传文件太大: " + fileHeader.Filename)
+			}
+		}
+	}
+	if limits.MaxFiles > 0 && count > limits.MaxFiles {
+		return TooLarge("上传文件太多")
+	}
+	return nil
+}
+
+// 超过 MaxBodySize 的读取错误转成 413
+func bodyError(err error) error {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return TooLarge("请求内容太大", err)
+	}
+	return BadRequest("读取请求内容失败", err)
+}
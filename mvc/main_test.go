@@ -0,0 +1,40 @@
+package mvc
+
+import (
+	"github.com/luoshanzhi/monster-go"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+// 测试用的控制器, 各个测试文件给它加方法, 路由里的控制器名是 Test
+type testController struct {
+}
+
+func TestMain(m *testing.M) {
+	//不在源码目录下创建 log 目录, 测试时也不输出框架日志
+	monster.SetLogMode("stdout")
+	monster.Init(map[string]interface{}{
+		"Test": (*testController)(nil),
+	})
+	monster.StatisticsLog = monster.NewNopLogger()
+	monster.AccessLog = monster.NewNopLogger()
+	monster.CommonLog = monster.NewNopLogger()
+	monster.ErrorLog = monster.NewNopLogger()
+	os.Exit(m.Run())
+}
+
+// 用 routeHandle 处理请求, 和 http.Server 里的调用方式一样
+func serve(server *Server, req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	routeHandle(server, w, req)
+	return w
+}
+
+// 只有一条路由的 Server
+func testServer(method string, pattern string, methodName string) *Server {
+	router := NewRouter()
+	router.Handle(method, pattern, "Test", methodName)
+	return &Server{Handler: router.Handler}
+}
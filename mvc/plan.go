@@ -0,0 +1,262 @@
+package mvc
+
+import (
+	"fmt"
+	"github.com/luoshanzhi/monster-go"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// 控制器方法和参数结构的反射信息只分析一次, 缓存起来给后续请求使用
+var (
+	methodPlans sync.Map //planKey => *methodPlan
+	paramPlans  sync.Map //reflect.Type => *paramPlan
+	requestType = reflect.TypeOf((*http.Request)(nil))
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+	strictType  = reflect.TypeOf((*Strict)(nil)).Elem()
+	markerTypes = map[reflect.Type]string{
+		reflect.TypeOf((*OPTIONS)(nil)).Elem(): "OPTIONS",
+		reflect.TypeOf((*GET)(nil)).Elem():     "GET",
+		reflect.TypeOf((*HEAD)(nil)).Elem():    "HEAD",
+		reflect.TypeOf((*POST)(nil)).Elem():    "POST",
+		reflect.TypeOf((*PUT)(nil)).Elem():     "PUT",
+		reflect.TypeOf((*DELETE)(nil)).Elem():  "DELETE",
+		reflect.TypeOf((*TRACE)(nil)).Elem():   "TRACE",
+		reflect.TypeOf((*CONNECT)(nil)).Elem(): "CONNECT",
+	}
+)
+
+type planKey struct {
+	controllerType reflect.Type
+	methodName     string
+}
+
+type methodPlan struct {
+	index     int             //控制器方法序号
+	allow     map[string]bool //允许的请求方法, 空代表所有
+	binders   []binder        //每个参数怎么取值
+	viewIndex int             //返回值里视图的位置, -1 代表没有
+	errIndex  int             //返回值里 error 的位置, -1 代表没有
+	outErr    string          //返回值格式错误
+}
+
+// 参数取值出错时返回 error, 不再调用控制器, 交给 Server.ErrorHandler 输出
+type binder func(server *Server, w http.ResponseWriter, req *http.Request, route Route) (reflect.Value, error)
+
+type paramPlan struct {
+	embeds [][]int //需要创建对象的内嵌匿名引用字段
+	fields []paramField
+	err    error //标签写错了, 比如 validate 规则不存在
+	strict bool  //参数结构实现了 mvc.Strict
+}
+
+type paramField struct {
+	index      []int
+	typ        reflect.Type
+	source     string   //取值来源: path, query, form, header, cookie, 为空代表按名字从 post 和 get 区取
+	key        string   //指定来源时的参数名
+	names      []string //没有指定来源时依次匹配的参数名
+	jsonName   string   //json 标签的名字, 不按名字兜底时只认这个
+	defaultVal string
+	hasDefault bool
+	layout     string //time.Time 的格式, 如 2006-01-02
+	isFile     bool
+	isList     bool   //切片或数组
+	nested     bool   //结构, map, 结构切片, 按 user[name], items[0][id] 这样的参数名取值
+	label      string //错误信息里的字段名
+	rules      []rule //validate 标签
+}
+
+// 按顺序查找的来源标签
+var sourceTags = []string{"path", "query", "form", "header", "cookie"}
+
+// 控制器方法不存在返回 nil
+func getMethodPlan(controllerType reflect.Type, methodName string) *methodPlan {
+	key := planKey{controllerType: controllerType, methodName: methodName}
+	if plan, ok := methodPlans.Load(key); ok {
+		return plan.(*methodPlan)
+	}
+	method, ok := controllerType.MethodByName(methodName)
+	if !ok {
+		return nil
+	}
+	funcType := method.Type
+	plan := &methodPlan{
+		index:     method.Index,
+		allow:     allowMethods(funcType),
+		viewIndex: -1,
+		errIndex:  -1,
+	}
+	switch numOut := funcType.NumOut(); {
+	case numOut == 1 && funcType.Out(0) == errorType:
+		plan.errIndex = 0
+	case numOut == 1:
+		plan.viewIndex = 0
+	case numOut == 2 && funcType.Out(1) == errorType:
+		plan.viewIndex = 0
+		plan.errIndex = 1
+	case numOut > 1:
+		plan.outErr = "路由函数只能返回 View, (View, error) 或 error"
+	}
+	//第0个参数是接收者
+	for i, paramNum := 1, funcType.NumIn(); i < paramNum; i++ {
+		plan.binders = append(plan.binders, newBinder(funcType.In(i)))
+	}
+	methodPlans.Store(key, plan)
+	return plan
+}
+
+func newBinder(in reflect.Type) binder {
+	//不写这句会报"reflect: Call using zero Value argument",用在 _ mvc.GET 等传参
+	zero := reflect.New(in).Elem()
+	if _, ok := markerTypes[in]; ok {
+		return func(server *Server, w http.ResponseWriter, req *http.Request, route Route) (reflect.Value, error) {
+			return zero, nil
+		}
+	}
+	if bind := resolverBinder(in); bind != nil {
+		return bind
+	}
+	if in == contextPtrType {
+		return func(server *Server, w http.ResponseWriter, req *http.Request, route Route) (reflect.Value, error) {
+			return reflect.ValueOf(acquireContext(server, w, req, route)), nil
+		}
+	}
+	if in == contextType {
+		return func(server *Server, w http.ResponseWriter, req *http.Request, route Route) (reflect.Value, error) {
+			return reflect.ValueOf(req.Context()), nil
+		}
+	}
+	if in.Kind() == reflect.Interface {
+		//http.ResponseWriter 等接口按实际类型判断
+		return func(server *Server, w http.ResponseWriter, req *http.Request, route Route) (reflect.Value, error) {
+			if reflect.TypeOf(w).AssignableTo(in) {
+				return reflect.ValueOf(w), nil
+			} else if requestType.AssignableTo(in) {
+				return reflect.ValueOf(req), nil
+			}
+			return zero, nil
+		}
+	}
+	if requestType.AssignableTo(in) {
+		return func(server *Server, w http.ResponseWriter, req *http.Request, route Route) (reflect.Value, error) {
+			return reflect.ValueOf(req), nil
+		}
+	}
+	if in.Kind() == reflect.Struct {
+		getParamPlan(in)
+		return func(server *Server, w http.ResponseWriter, req *http.Request, route Route) (reflect.Value, error) {
+			return parseParam(server, req, route, in)
+		}
+	}
+	return func(server *Server, w http.ResponseWriter, req *http.Request, route Route) (reflect.Value, error) {
+		return zero, nil
+	}
+}
+
+// 获取所有字段, 包括内嵌匿名字段
+func getParamPlan(in reflect.Type) *paramPlan {
+	if plan, ok := paramPlans.Load(in); ok {
+		return plan.(*paramPlan)
+	}
+	plan := &paramPlan{strict: reflect.PtrTo(in).Implements(strictType)}
+	var walk func(structType reflect.Type, parent []int)
+	walk = func(structType reflect.Type, parent []int) {
+		for i, numField := 0, structType.NumField(); i < numField; i++ {
+			structField := structType.Field(i)
+			index := append(append([]int{}, parent...), i)
+			sfType := structField.Type
+			//不导出的字段没法赋值, 内嵌的不导出结构里导出的字段可以
+			if structField.PkgPath != "" && !(structField.Anonymous && sfType.Kind() == reflect.Struct) {
+				continue
+			}
+			if structField.Anonymous {
+				if sfType.Kind() == reflect.Ptr {
+					plan.embeds = append(plan.embeds, index)
+					sfType = sfType.Elem()
+				}
+				if sfType.Kind() == reflect.Struct {
+					walk(sfType, index)
+				}
+				continue
+			}
+			field := paramField{
+				index:  index,
+				typ:    sfType,
+				isFile: isFile(sfType),
+				nested: isNested(sfType),
+			}
+			listType := sfType
+			if listType.Kind() == reflect.Ptr {
+				listType = listType.Elem()
+			}
+			field.isList = (listType.Kind() == reflect.Slice || listType.Kind() == reflect.Array) && !isConvertible(listType)
+			field.defaultVal, field.hasDefault = structField.Tag.Lookup("default")
+			field.layout = structField.Tag.Get("layout")
+			for _, tag := range sourceTags {
+				if key, ok := structField.Tag.Lookup(tag); ok {
+					field.source = tag
+					field.key = strings.TrimSpace(key)
+					if field.key == "" {
+						field.key = monster.FirstLower(structField.Name)
+					}
+					break
+				}
+			}
+			if field.source == "" {
+				//json:"-" 不按名字取值, json:"name" 先按 json 名字取值, 再兼容首字母大小写
+				jsonName := strings.TrimSpace(strings.Split(structField.Tag.Get("json"), ",")[0])
+				if jsonName == "-" {
+					if !field.hasDefault {
+						continue
+					}
+				} else {
+					field.jsonName = jsonName
+					for _, name := range []string{jsonName, monster.FirstLower(structField.Name), structField.Name} {
+						if name != "" && !inStrings(field.names, name) {
+							field.names = append(field.names, name)
+						}
+					}
+				}
+			}
+			field.label = field.key
+			if field.label == "" {
+				field.label = field.jsonName
+			}
+			if field.label == "" {
+				field.label = monster.FirstLower(structField.Name)
+			}
+			if tag, ok := structField.Tag.Lookup("validate"); ok {
+				rules, err := parseRules(tag)
+				if err != nil && plan.err == nil {
+					plan.err = fmt.Errorf("%s.%s: %w", in.String(), structField.Name, err)
+				}
+				field.rules = rules
+			}
+			plan.fields = append(plan.fields, field)
+		}
+	}
+	walk(in, nil)
+	paramPlans.Store(in, plan)
+	return plan
+}
+
+func hasSourceTag(structField reflect.StructField) bool {
+	for _, tag := range sourceTags {
+		if _, ok := structField.Tag.Lookup(tag); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func inStrings(list []string, str string) bool {
+	for _, item := range list {
+		if item == str {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+package mvc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type sourceParam struct {
+	Id       int    `path:"id"`
+	Page     int    `query:"page" default:"1"`
+	Title    string `form:"title"`
+	Token    string `header:"X-Token"`
+	Sid      string `cookie:"sid"`
+	NickName string `json:"nick_name"`
+	Skip     string `json:"-"`
+	Name     string
+}
+
+func (the *testController) Source(param sourceParam) *JsonView {
+	return JSON(param)
+}
+
+func bindSource(t *testing.T, server *Server, req *http.Request) sourceParam {
+	w := serve(server, req)
+	var param sourceParam
+	if err := json.Unmarshal(w.Body.Bytes(), &param); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("status %d, body %s", w.Code, w.Body.String())
+	}
+	return param
+}
+
+func TestBindSource(t *testing.T) {
+	router := NewRouter()
+	router.Handle(http.MethodPost, "/source/{id}", "Test", "Source")
+	server := &Server{Handler: router.Handler}
+	//page 只认 url 上的, title 只认 post 的
+	req := httptest.NewRequest(http.MethodPost, "/source/7?title=get&nick_name=nick&skip=x&Name=n", strings.NewReader("page=9&title=post"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-Token", "token")
+	req.AddCookie(&http.Cookie{Name: "sid", Value: "sid"})
+	param := bindSource(t, server, req)
+	want := sourceParam{Id: 7, Page: 1, Title: "post", Token: "token", Sid: "sid", NickName: "nick", Name: "n"}
+	if param != want {
+		t.Fatalf("param = %+v, want %+v", param, want)
+	}
+}
+
+// ExplicitBinding 时没有来源标签的字段只按 json 名字取值
+func TestBindExplicit(t *testing.T) {
+	router := NewRouter()
+	router.Handle(http.MethodGet, "/source/{id}", "Test", "Source")
+	server := &Server{Handler: router.Handler, ExplicitBinding: true}
+	param := bindSource(t, server, httptest.NewRequest(http.MethodGet, "/source/1?nickName=a&Name=n&page=3", nil))
+	if param.NickName != "" || param.Name != "" || param.Page != 3 {
+		t.Fatalf("param = %+v", param)
+	}
+	param = bindSource(t, server, httptest.NewRequest(http.MethodGet, "/source/1?nick_name=a", nil))
+	if param.NickName != "a" {
+		t.Fatalf("param = %+v", param)
+	}
+}
+
+// json body 里的同名字段不能伪造 header, cookie, query 等来源
+func TestBindSourceNotFromBody(t *testing.T) {
+	router := NewRouter()
+	router.Handle(http.MethodPost, "/source/{id}", "Test", "Source")
+	server := &Server{Handler: router.Handler}
+	body := `{"Id":5,"Page":9,"Title":"forged","Token":"forged","Sid":"forged","nick_name":"nick"}`
+	newReq := func() *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/source/7", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		return req
+	}
+	param := bindSource(t, server, newReq())
+	want := sourceParam{Id: 7, Page: 1, NickName: "nick"}
+	if param != want {
+		t.Fatalf("param = %+v, want %+v", param, want)
+	}
+	server.StrictBinding = true
+	status, fields := validateErrors(serve(server, newReq()))
+	if status != http.StatusBadRequest || fields != "Id:unknown,Page:unknown,Sid:unknown,Title:unknown,Token:unknown" {
+		t.Fatalf("status %d, fields %s", status, fields)
+	}
+}
+
+type benchParam struct {
+	Id   int `path:"id"`
+	Name string
+	Page int `query:"page" default:"1"`
+}
+
+func (the *testController) Bench(req *http.Request, param benchParam, _ GET) *JsonView {
+	return JSON(param.Id)
+}
+
+// 常见的 GET 请求: 一个路径参数, 三个字段的参数结构, 对比缓存和每次重新分析的开销
+func benchRouteHandle(b *testing.B, cached bool) {
+	router := NewRouter()
+	router.Get("/bench/{id}", "Test", "Bench")
+	server := &Server{Handler: router.Handler}
+	req := httptest.NewRequest(http.MethodGet, "/bench/7?name=abc&page=2", nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if !cached {
+			//每次都重新分析控制器方法和参数结构, 相当于缓存之前的做法
+			methodPlans.Range(func(key, value interface{}) bool {
+				methodPlans.Delete(key)
+				return true
+			})
+			paramPlans.Range(func(key, value interface{}) bool {
+				paramPlans.Delete(key)
+				return true
+			})
+		}
+		w := httptest.NewRecorder()
+		routeHandle(server, w, req)
+		if w.Code != http.StatusOK {
+			b.Fatalf("status %d, body %s", w.Code, w.Body.String())
+		}
+	}
+}
+
+func BenchmarkRouteHandle(b *testing.B) {
+	benchRouteHandle(b, true)
+}
+
+func BenchmarkRouteHandleUncached(b *testing.B) {
+	benchRouteHandle(b, false)
+}
@@ -0,0 +1,47 @@
+package mvc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"sync"
+)
+
+// Resolver 给控制器方法的参数取值, 用 RegisterResolver 注册, 比如当前登录用户, 会话, 租户
+// 返回 error 时不再调用控制器, 按 Server.ErrorHandler 输出, 状态码按 StatusOf, 如返回 mvc.Unauthorized(...) 输出 401
+type Resolver func(w http.ResponseWriter, req *http.Request) (reflect.Value, error)
+
+var (
+	resolvers   sync.Map //reflect.Type => Resolver
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+)
+
+// RegisterResolver 注册控制器参数类型的取值方式, 优先于内置的参数结构解析, 返回值类型必须可以赋值给 typ, 在 mvc.Serve 之前注册
+// 如 mvc.RegisterResolver(reflect.TypeOf((*CurrentUser)(nil)), func(w http.ResponseWriter, req *http.Request) (reflect.Value, error) {...})
+func RegisterResolver(typ reflect.Type, resolver Resolver) {
+	resolvers.Store(typ, resolver)
+}
+
+// 没有注册返回 nil
+func resolverBinder(in reflect.Type) binder {
+	resolver, ok := resolvers.Load(in)
+	if !ok {
+		return nil
+	}
+	zero := reflect.New(in).Elem()
+	return func(server *Server, w http.ResponseWriter, req *http.Request, route Route) (reflect.Value, error) {
+		value, err := resolver.(Resolver)(w, req)
+		if err != nil {
+			return zero, err
+		}
+		//返回 reflect.Value{} 代表零值
+		if !value.IsValid() {
+			return zero, nil
+		}
+		if !value.Type().AssignableTo(in) {
+			return zero, errors.New("resolver returns " + value.Type().String() + ", want " + in.String())
+		}
+		return value, nil
+	}
+}
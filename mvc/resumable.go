@@ -0,0 +1,357 @@
+package mvc
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 断点续传, 按 tus 1.0.0 协议实现 core, creation, expiration, termination:
+// POST   BasePath       创建上传, Upload-Length 文件大小, Upload-Metadata 附加信息, 返回 Location
+// HEAD   BasePath/{id}  查询进度, 返回 Upload-Offset
+// PATCH  BasePath/{id}  从 Upload-Offset 开始上传一段, Content-Type: application/offset+octet-stream
+// DELETE BasePath/{id}  取消上传
+// 用 Router.Mount(BasePath, upload) 挂载, 单次 PATCH 的大小受 Limits.MaxBodySize 限制
+
+const tusVersion = "1.0.0"
+
+var resumableIdReg = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// ResumableUpload 断点续传处理器, 分片保存在本地目录, 全部上传完成后调用 OnComplete
+type ResumableUpload struct {
+	Dir      string        //临时目录
+	BasePath string        //挂载路径, 如 /files, 用来生成 Location
+	MaxSize  int64         //单个文件最大字节数, 0 不限制
+	Expire   time.Duration //多久没有继续上传就过期删除, 默认 24 小时
+	//上传完成时调用, 返回 nil 后临时文件会被删除, 需要保留就在回调里移动或复制 file.Path
+	//返回 error 时按 Server.ErrorHandler 输出, 临时文件保留到过期
+	OnComplete func(req *http.Request, file *ResumableFile) error
+	mutex      sync.Mutex
+	locks      map[string]*sync.Mutex
+	cleanAt    time.Time
+}
+
+// ResumableFile 一个上传的状态, 保存在 Dir/{id}.info
+type ResumableFile struct {
+	Id        string
+	Size      int64
+	Offset    int64
+	Metadata  map[string]string //Upload-Metadata, 如 filename
+	ExpiresAt time.Time
+	Path      string `json:"-"` //已上传内容的本地路径
+}
+
+func NewResumableUpload(dir string, basePath string) *ResumableUpload {
+	return &ResumableUpload{Dir: dir, BasePath: basePath}
+}
+
+func (the *ResumableUpload) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+	if req.Method == http.MethodOptions {
+		w.Header().Set("Tus-Version", tusVersion)
+		w.Header().Set("Tus-Extension", "creation,expiration,termination")
+		if the.MaxSize > 0 {
+			w.Header().Set("Tus-Max-Size", strconv.FormatInt(the.MaxSize, 10))
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if req.Header.Get("Tus-Resumable") != tusVersion {
+		w.Header().Set("Tus-Version", tusVersion)
+		ResponseOut(w, http.StatusPreconditionFailed, nil, "不支持的 Tus-Resumable 版本")
+		return
+	}
+	id := strings.Trim(strings.TrimPrefix(req.URL.Path, strings.TrimRight(the.BasePath, "/")), "/")
+	var err error
+	switch {
+	case id == "" && req.Method == http.MethodPost:
+		err = the.create(w, req)
+	case id == "":
+		err = &MethodNotAllowedError{Allow: []string{http.MethodPost}}
+	case !resumableIdReg.MatchString(id):
+		err = ErrNotFound
+	case req.Method == http.MethodHead:
+		err = the.head(w, id)
+	case req.Method == http.MethodPatch:
+		err = the.patch(w, req, id)
+	case req.Method == http.MethodDelete:
+		err = the.delete(w, id)
+	default:
+		err = &MethodNotAllowedError{Allow: []string{http.MethodHead, http.MethodPatch, http.MethodDelete}}
+	}
+	if err != nil {
+		var methodErr *MethodNotAllowedError
+		if errors.As(err, &methodErr) {
+			w.Header().Set("Allow", strings.Join(append(methodErr.Allow, http.MethodOptions), ", "))
+		}
+		if server := serverOf(req); server != nil {
+			errorOut(server, w, req, err)
+		} else {
+			DefaultErrorHandler(w, req, err)
+		}
+	}
+}
+
+func (the *ResumableUpload) create(w http.ResponseWriter, req *http.Request) error {
+	the.cleanExpired()
+	size, err := strconv.ParseInt(req.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || size < 0 {
+		return BadRequest("Upload-Length 错误")
+	}
+	if the.MaxSize > 0 && size > the.MaxSize {
+		return TooLarge("上传文件太大")
+	}
+	metadata, err := parseMetadata(req.Header.Get("Upload-Metadata"))
+	if err != nil {
+		return BadRequest("Upload-Metadata 错误", err)
+	}
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return err
+	}
+	file := &ResumableFile{
+		Id:        hex.EncodeToString(buf),
+		Size:      size,
+		Metadata:  metadata,
+		ExpiresAt: time.Now().Add(the.expire()),
+	}
+	if err := os.MkdirAll(the.Dir, os.ModePerm); err != nil {
+		return err
+	}
+	data, err := os.OpenFile(the.dataPath(file.Id), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	data.Close()
+	if err := the.saveInfo(file); err != nil {
+		return err
+	}
+	w.Header().Set("Location", strings.TrimRight(the.BasePath, "/")+"/"+file.Id)
+	w.Header().Set("Upload-Expires", file.ExpiresAt.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusCreated)
+	return nil
+}
+
+func (the *ResumableUpload) head(w http.ResponseWriter, id string) error {
+	file, err := the.loadInfo(id)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Upload-Offset", strconv.FormatInt(file.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(file.Size, 10))
+	w.Header().Set("Upload-Expires", file.ExpiresAt.UTC().Format(http.TimeFormat))
+	if len(file.Metadata) > 0 {
+		w.Header().Set("Upload-Metadata", formatMetadata(file.Metadata))
+	}
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+func (the *ResumableUpload) patch(w http.ResponseWriter, req *http.Request, id string) error {
+	if req.Header.Get("Content-Type") != "application/offset+octet-stream" {
+		return NewError(http.StatusUnsupportedMediaType, "Content-Type 必须是 application/offset+octet-stream")
+	}
+	//先确认上传存在, 不存在的 id 不创建锁, 防止随便请求就让 locks 一直变大
+	if _, err := the.loadInfo(id); err != nil {
+		return err
+	}
+	//同一个上传不能同时写
+	lock := the.lock(id)
+	if !lock.TryLock() {
+		return NewError(http.StatusConflict, "上传正在进行")
+	}
+	defer lock.Unlock()
+	//拿到锁之前可能已经完成或删除了, 重新读取
+	file, err := the.loadInfo(id)
+	if err != nil {
+		the.dropLock(id)
+		return err
+	}
+	offset, err := strconv.ParseInt(req.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset != file.Offset {
+		return NewError(http.StatusConflict, "Upload-Offset 和已上传的大小不一致")
+	}
+	data, err := os.OpenFile(file.Path, os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	//网络中断时已经写入的部分也算数, 下次从新的 Upload-Offset 继续
+	written, copyErr := func() (int64, error) {
+		defer data.Close()
+		if _, err := data.Seek(offset, io.SeekStart); err != nil {
+			return 0, err
+		}
+		return io.Copy(data, io.LimitReader(req.Body, file.Size-offset))
+	}()
+	file.Offset += written
+	file.ExpiresAt = time.Now().Add(the.expire())
+	if err := the.saveInfo(file); err != nil {
+		return err
+	}
+	if copyErr != nil {
+		w.Header().Set("Upload-Offset", strconv.FormatInt(file.Offset, 10))
+		return bodyError(copyErr)
+	}
+	if file.Offset == file.Size && the.OnComplete != nil {
+		if err := the.OnComplete(req, file); err != nil {
+			return err
+		}
+		the.remove(id)
+	}
+	w.Header().Set("Upload-Offset", strconv.FormatInt(file.Offset, 10))
+	w.Header().Set("Upload-Expires", file.ExpiresAt.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (the *ResumableUpload) delete(w http.ResponseWriter, id string) error {
+	if _, err := the.loadInfo(id); err != nil {
+		return err
+	}
+	lock := the.lock(id)
+	if !lock.TryLock() {
+		return NewError(http.StatusConflict, "上传正在进行")
+	}
+	defer lock.Unlock()
+	if _, err := the.loadInfo(id); err != nil {
+		the.dropLock(id)
+		return err
+	}
+	the.remove(id)
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (the *ResumableUpload) expire() time.Duration {
+	if the.Expire <= 0 {
+		return 24 * time.Hour
+	}
+	return the.Expire
+}
+
+func (the *ResumableUpload) dataPath(id string) string {
+	return filepath.Join(the.Dir, id)
+}
+
+func (the *ResumableUpload) infoPath(id string) string {
+	return filepath.Join(the.Dir, id+".info")
+}
+
+func (the *ResumableUpload) lock(id string) *sync.Mutex {
+	the.mutex.Lock()
+	defer the.mutex.Unlock()
+	if the.locks == nil {
+		the.locks = make(map[string]*sync.Mutex)
+	}
+	lock, ok := the.locks[id]
+	if !ok {
+		lock = &sync.Mutex{}
+		the.locks[id] = lock
+	}
+	return lock
+}
+
+// 不存在返回 404, 过期返回 410 并删除
+func (the *ResumableUpload) loadInfo(id string) (*ResumableFile, error) {
+	content, err := os.ReadFile(the.infoPath(id))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	file := &ResumableFile{}
+	if err := json.Unmarshal(content, file); err != nil {
+		return nil, err
+	}
+	if time.Now().After(file.ExpiresAt) {
+		the.remove(id)
+		return nil, NewError(http.StatusGone, "上传已过期")
+	}
+	file.Path = the.dataPath(id)
+	return file, nil
+}
+
+// 先写临时文件再改名, 避免写到一半的 info
+func (the *ResumableUpload) saveInfo(file *ResumableFile) error {
+	content, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+	tmp := the.infoPath(file.Id) + ".tmp"
+	if err := os.WriteFile(tmp, content, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, the.infoPath(file.Id))
+}
+
+func (the *ResumableUpload) remove(id string) {
+	os.Remove(the.infoPath(id))
+	os.Remove(the.dataPath(id))
+	the.dropLock(id)
+}
+
+// 上传已经不存在了, 删除它的锁
+func (the *ResumableUpload) dropLock(id string) {
+	the.mutex.Lock()
+	delete(the.locks, id)
+	the.mutex.Unlock()
+}
+
+// 创建上传时顺便清理过期的上传, 最多一分钟一次
+func (the *ResumableUpload) cleanExpired() {
+	the.mutex.Lock()
+	if time.Since(the.cleanAt) < time.Minute {
+		the.mutex.Unlock()
+		return
+	}
+	the.cleanAt = time.Now()
+	the.mutex.Unlock()
+	infos, _ := filepath.Glob(filepath.Join(the.Dir, "*.info"))
+	for _, info := range infos {
+		id := strings.TrimSuffix(filepath.Base(info), ".info")
+		if resumableIdReg.MatchString(id) {
+			//过期的在 loadInfo 里删除
+			the.loadInfo(id)
+		}
+	}
+}
+
+// Upload-Metadata: key base64(value),key2 base64(value2)
+func parseMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	for _, item := range strings.Split(header, ",") {
+		fields := strings.Fields(item)
+		if len(fields) == 0 {
+			continue
+		}
+		var value []byte
+		if len(fields) > 1 {
+			var err error
+			if value, err = base64.StdEncoding.DecodeString(fields[1]); err != nil {
+				return nil, err
+			}
+		}
+		metadata[fields[0]] = string(value)
+	}
+	return metadata, nil
+}
+
+func formatMetadata(metadata map[string]string) string {
+	var list []string
+	for key, value := range metadata {
+		list = append(list, key+" "+base64.StdEncoding.EncodeToString([]byte(value)))
+	}
+	return strings.Join(list, ",")
+}
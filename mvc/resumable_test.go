@@ -0,0 +1,161 @@
+package mvc
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type tusClient struct {
+	t      *testing.T
+	server *Server
+}
+
+func (the tusClient) do(method string, path string, body string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Tus-Resumable", tusVersion)
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+	return serve(the.server, req)
+}
+
+func (the tusClient) patch(location string, offset string, body string) *httptest.ResponseRecorder {
+	return the.do(http.MethodPatch, location, body, map[string]string{
+		"Content-Type":  "application/offset+octet-stream",
+		"Upload-Offset": offset,
+	})
+}
+
+func newTusClient(t *testing.T, upload *ResumableUpload) tusClient {
+	router := NewRouter()
+	router.Mount("/files", upload).Limits(Limits{MaxBodySize: 5})
+	return tusClient{t: t, server: &Server{Handler: router.Handler}}
+}
+
+func TestResumableUpload(t *testing.T) {
+	dir := t.TempDir()
+	upload := NewResumableUpload(dir, "/files")
+	var completed string
+	upload.OnComplete = func(req *http.Request, file *ResumableFile) error {
+		content, err := os.ReadFile(file.Path)
+		completed = string(content) + "|" + file.Metadata["filename"]
+		return err
+	}
+	client := newTusClient(t, upload)
+
+	if w := client.do(http.MethodOptions, "/files", "", nil); w.Code != http.StatusNoContent || w.Header().Get("Tus-Version") != tusVersion {
+		t.Fatalf("options: status %d", w.Code)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/files", nil)
+	if w := serve(client.server, req); w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("no Tus-Resumable: status %d", w.Code)
+	}
+	//YS50eHQ= 是 a.txt
+	w := client.do(http.MethodPost, "/files", "", map[string]string{"Upload-Length": "8", "Upload-Metadata": "filename YS50eHQ="})
+	location := w.Header().Get("Location")
+	if w.Code != http.StatusCreated || !strings.HasPrefix(location, "/files/") {
+		t.Fatalf("create: status %d, location %s", w.Code, location)
+	}
+	//超过 MaxBodySize 的部分不写入, 已经写入的保留
+	if w := client.patch(location, "0", "abcdefg"); w.Code != http.StatusRequestEntityTooLarge || w.Header().Get("Upload-Offset") != "5" {
+		t.Fatalf("patch too large: status %d, offset %s", w.Code, w.Header().Get("Upload-Offset"))
+	}
+	if w := client.do(http.MethodHead, location, "", nil); w.Code != http.StatusOK || w.Header().Get("Upload-Offset") != "5" || w.Header().Get("Upload-Length") != "8" {
+		t.Fatalf("head: status %d, offset %s", w.Code, w.Header().Get("Upload-Offset"))
+	}
+	if w := client.patch(location, "3", "xyz"); w.Code != http.StatusConflict {
+		t.Fatalf("patch wrong offset: status %d", w.Code)
+	}
+	if w := client.do(http.MethodPatch, location, "fgh", map[string]string{"Upload-Offset": "5"}); w.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("patch wrong content type: status %d", w.Code)
+	}
+	if w := client.patch(location, "5", "fgh"); w.Code != http.StatusNoContent || w.Header().Get("Upload-Offset") != "8" {
+		t.Fatalf("patch: status %d, offset %s", w.Code, w.Header().Get("Upload-Offset"))
+	}
+	if completed != "abcdefgh|a.txt" {
+		t.Fatalf("completed = %q", completed)
+	}
+	//完成后删除临时文件
+	if w := client.do(http.MethodHead, location, "", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("head after complete: status %d", w.Code)
+	}
+	if w := client.do(http.MethodGet, location, "", nil); w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") == "" {
+		t.Fatalf("get: status %d", w.Code)
+	}
+	if w := client.do(http.MethodHead, "/files/../x", "", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("bad id: status %d", w.Code)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("dir not empty: %d files", len(entries))
+	}
+}
+
+func TestResumableUploadLimits(t *testing.T) {
+	upload := NewResumableUpload(t.TempDir(), "/files")
+	upload.MaxSize = 10
+	client := newTusClient(t, upload)
+	if w := client.do(http.MethodPost, "/files", "", map[string]string{"Upload-Length": "11"}); w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("create too large: status %d", w.Code)
+	}
+	if w := client.do(http.MethodPost, "/files", "", map[string]string{"Upload-Length": "x"}); w.Code != http.StatusBadRequest {
+		t.Fatalf("create bad length: status %d", w.Code)
+	}
+}
+
+func TestResumableUploadExpireAndDelete(t *testing.T) {
+	dir := t.TempDir()
+	upload := NewResumableUpload(dir, "/files")
+	upload.Expire = time.Hour
+	client := newTusClient(t, upload)
+	create := func() string {
+		return client.do(http.MethodPost, "/files", "", map[string]string{"Upload-Length": "4"}).Header().Get("Location")
+	}
+	location := create()
+	if w := client.do(http.MethodDelete, location, "", nil); w.Code != http.StatusNoContent {
+		t.Fatalf("delete: status %d", w.Code)
+	}
+	if w := client.do(http.MethodHead, location, "", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("head after delete: status %d", w.Code)
+	}
+	//改成已经过期
+	location = create()
+	id := filepath.Base(location)
+	file, err := upload.loadInfo(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.ExpiresAt = time.Now().Add(-time.Minute)
+	if err := upload.saveInfo(file); err != nil {
+		t.Fatal(err)
+	}
+	if w := client.patch(location, "0", "ab"); w.Code != http.StatusGone {
+		t.Fatalf("patch expired: status %d", w.Code)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("expired upload not removed: %d files", len(entries))
+	}
+}
+
+// 不存在的上传不能在 locks 里留下记录
+func TestResumableUploadUnknownId(t *testing.T) {
+	upload := NewResumableUpload(t.TempDir(), "/files")
+	client := newTusClient(t, upload)
+	for i := 0; i < 10; i++ {
+		location := fmt.Sprintf("/files/%032x", i)
+		if w := client.patch(location, "0", "a"); w.Code != http.StatusNotFound {
+			t.Fatalf("patch: status %d", w.Code)
+		}
+		if w := client.do(http.MethodDelete, location, "", nil); w.Code != http.StatusNotFound {
+			t.Fatalf("delete: status %d", w.Code)
+		}
+	}
+	if len(upload.locks) != 0 {
+		t.Fatalf("locks = %d, want 0", len(upload.locks))
+	}
+}
@@ -0,0 +1,362 @@
+package mvc
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Router 声明式路由, 按路径前缀树匹配, 用法: &mvc.Server{Handler: router.Handler}
+// 路径格式: /users/{id}, /users/{id:[0-9]+}, /static/*filepath(只能放最后)
+type Router struct {
+	NameCase NameCase //AutoRoutes 生成路径的命名方式, 默认 KebabCase
+	store    *routerStore
+	prefix   string
+}
+
+type NameCase int
+
+const (
+	KebabCase NameCase = iota //upload-files
+	CamelCase                 //uploadFiles
+)
+
+type routerStore struct {
+	guard sync.RWMutex
+	root  *node
+	names map[string]*RouteEntry
+}
+
+// RouteEntry 一条注册的路由
+type RouteEntry struct {
+	method         string
+	pattern        string
+	name           string
+	controllerName string
+	methodName     string
+	limits         *Limits
+	handler        http.Handler //Mount 挂载的处理器
+	store          *routerStore
+}
+
+type node struct {
+	static    map[string]*node
+	params    []*node
+	paramName string
+	paramReg  *regexp.Regexp
+	wildcard  *node
+	entries   map[string]*RouteEntry //key: 请求方法, * 代表所有
+}
+
+func NewRouter() *Router {
+	return &Router{
+		store: &routerStore{
+			root:  &node{},
+			names: make(map[string]*RouteEntry),
+		},
+	}
+}
+
+// Group 共用路径前缀的路由组, fn 不为空时直接在组内注册
+func (the *Router) Group(prefix string, fn ...func(group *Router)) *Router {
+	group := &Router{
+		NameCase: the.NameCase,
+		store:    the.store,
+		prefix:   joinPath(the.prefix, prefix),
+	}
+	for _, f := range fn {
+		f(group)
+	}
+	return group
+}
+
+// Handle method 为空或 * 代表接受所有请求方法
+func (the *Router) Handle(method string, pattern string, controllerName string, methodName string) *RouteEntry {
+	method = strings.ToUpper(strings.TrimSpace(method))
+	if method == "" {
+		method = "*"
+	}
+	pattern = joinPath(the.prefix, pattern)
+	entry := &RouteEntry{
+		method:         method,
+		pattern:        pattern,
+		controllerName: controllerName,
+		methodName:     methodName,
+		store:          the.store,
+	}
+	//防止并发写map异常
+	the.store.guard.Lock()
+	defer the.store.guard.Unlock()
+	n := the.store.root
+	segments := splitPath(pattern)
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, "*") {
+			if i != len(segments)-1 {
+				panic(errors.New("路由通配符只能放最后: " + pattern))
+			}
+			name := segment[1:]
+			if name == "" {
+				name = "*"
+			}
+			if n.wildcard == nil {
+				n.wildcard = &node{paramName: name}
+			} else if n.wildcard.paramName != name {
+				panic(errors.New("路由通配符名称冲突: " + pattern))
+			}
+			n = n.wildcard
+		} else if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			name, reg := parseParamSegment(segment)
+			var child *node
+			for _, item := range n.params {
+				if item.paramName == name && regString(item.paramReg) == regString(reg) {
+					child = item
+					break
+				}
+			}
+			if child == nil {
+				child = &node{paramName: name, paramReg: reg}
+				n.params = append(n.params, child)
+			}
+			n = child
+		} else {
+			if n.static == nil {
+				n.static = make(map[string]*node)
+			}
+			child, ok := n.static[segment]
+			if !ok {
+				child = &node{}
+				n.static[segment] = child
+			}
+			n = child
+		}
+	}
+	if n.entries == nil {
+		n.entries = make(map[string]*RouteEntry)
+	}
+	if _, ok := n.entries[method]; ok {
+		panic(errors.New("路由重复注册: " + method + " " + pattern))
+	}
+	n.entries[method] = entry
+	return entry
+}
+
+func (the *Router) Get(pattern string, controllerName string, methodName string) *RouteEntry {
+	return the.Handle(http.MethodGet, pattern, controllerName, methodName)
+}
+
+func (the *Router) Post(pattern string, controllerName string, methodName string) *RouteEntry {
+	return the.Handle(http.MethodPost, pattern, controllerName, methodName)
+}
+
+func (the *Router) Put(pattern string, controllerName string, methodName string) *RouteEntry {
+	return the.Handle(http.MethodPut, pattern, controllerName, methodName)
+}
+
+func (the *Router) Patch(pattern string, controllerName string, methodName string) *RouteEntry {
+	return the.Handle(http.MethodPatch, pattern, controllerName, methodName)
+}
+
+func (the *Router) Delete(pattern string, controllerName string, methodName string) *RouteEntry {
+	return the.Handle(http.MethodDelete, pattern, controllerName, methodName)
+}
+
+func (the *Router) Head(pattern string, controllerName string, methodName string) *RouteEntry {
+	return the.Handle(http.MethodHead, pattern, controllerName, methodName)
+}
+
+func (the *Router) Options(pattern string, controllerName string, methodName string) *RouteEntry {
+	return the.Handle(http.MethodOptions, pattern, controllerName, methodName)
+}
+
+func (the *Router) Any(pattern string, controllerName string, methodName string) *RouteEntry {
+	return the.Handle("*", pattern, controllerName, methodName)
+}
+
+// Mount 把 http.Handler 挂载到 prefix 和它下面的所有路径, 接受所有请求方法
+// 前置拦截器和 Limits 仍然生效, 不经过控制器, 如 r.Mount("/files", mvc.NewResumableUpload("./upload/tmp", "/files"))
+func (the *Router) Mount(prefix string, handler http.Handler) *RouteEntry {
+	entry := the.Handle("*", joinPath(prefix, "*path"), "", "")
+	entry.handler = handler
+	return entry
+}
+
+// Name 命名路由, 可以用 Router.URL 反向生成路径
+func (the *RouteEntry) Name(name string) *RouteEntry {
+	//防止并发写map异常
+	the.store.guard.Lock()
+	defer the.store.guard.Unlock()
+	if _, ok := the.store.names[name]; ok {
+		panic(errors.New("路由名称重复: " + name))
+	}
+	the.name = name
+	the.store.names[name] = the
+	return the
+}
+
+// Limits 这条路由的请求大小限制, 不为 0 的配置覆盖 Server.Limits, 比如上传接口单独放大 MaxBodySize
+func (the *RouteEntry) Limits(limits Limits) *RouteEntry {
+	the.limits = &limits
+	return the
+}
+
+// URL 按命名路由生成路径, params 填充路径参数
+func (the *Router) URL(name string, params map[string]string) (string, error) {
+	the.store.guard.RLock()
+	entry, ok := the.store.names[name]
+	the.store.guard.RUnlock()
+	if !ok {
+		return "", errors.New("路由名称不存在: " + name)
+	}
+	segments := splitPath(entry.pattern)
+	for i, segment := range segments {
+		var paramName string
+		var reg *regexp.Regexp
+		if strings.HasPrefix(segment, "*") {
+			paramName = segment[1:]
+			if paramName == "" {
+				paramName = "*"
+			}
+		} else if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			paramName, reg = parseParamSegment(segment)
+		} else {
+			continue
+		}
+		val, ok := params[paramName]
+		if !ok {
+			return "", errors.New("缺少路由参数: " + paramName)
+		}
+		if reg != nil && !reg.MatchString(val) {
+			return "", errors.New("路由参数格式错误: " + paramName)
+		}
+		//参数里的 ?, #, 空格, % 等要转义, 通配符按 / 分段转义
+		if strings.HasPrefix(segment, "*") {
+			parts := strings.Split(strings.Trim(val, "/"), "/")
+			for j, part := range parts {
+				parts[j] = url.PathEscape(part)
+			}
+			segments[i] = strings.Join(parts, "/")
+		} else {
+			segments[i] = url.PathEscape(val)
+		}
+	}
+	return "/" + strings.Join(segments, "/"), nil
+}
+
+// Handler 满足 Server.Handler
+func (the *Router) Handler(req *http.Request) (Route, error) {
+	var route Route
+	method := strings.ToUpper(req.Method)
+	segments := splitEscapedPath(req.URL.EscapedPath())
+	values := make([]string, 0, 4)
+	the.store.guard.RLock()
+	defer the.store.guard.RUnlock()
+	n, values := the.store.root.match(segments, values)
+	if n == nil {
+		return route, ErrNotFound
+	}
+	entry, ok := n.entries[method]
+	if !ok {
+		entry, ok = n.entries["*"]
+	}
+	if !ok && method == http.MethodHead {
+		entry, ok = n.entries[http.MethodGet]
+	}
+	if !ok {
+		var allow []string
+		for m := range n.entries {
+			allow = append(allow, m)
+		}
+		return route, &MethodNotAllowedError{Allow: allow}
+	}
+	route.ControllerName = entry.controllerName
+	route.MethodName = entry.methodName
+	route.Name = entry.name
+	route.Limits = entry.limits
+	route.Handler = entry.handler
+	if len(values) > 0 {
+		route.Params = make(map[string]string, len(values)/2)
+		for i := 0; i < len(values); i += 2 {
+			route.Params[values[i]] = values[i+1]
+		}
+	}
+	return route, nil
+}
+
+// 优先级: 静态 > 参数 > 通配符, 匹配失败会回溯
+func (the *node) match(segments []string, values []string) (*node, []string) {
+	if len(segments) == 0 {
+		if the.entries != nil {
+			return the, values
+		}
+		if the.wildcard != nil && the.wildcard.entries != nil {
+			return the.wildcard, append(values, the.wildcard.paramName, "")
+		}
+		return nil, values
+	}
+	segment := segments[0]
+	if child, ok := the.static[segment]; ok {
+		if n, vals := child.match(segments[1:], values); n != nil {
+			return n, vals
+		}
+	}
+	for _, child := range the.params {
+		if child.paramReg != nil && !child.paramReg.MatchString(segment) {
+			continue
+		}
+		if n, vals := child.match(segments[1:], append(values, child.paramName, segment)); n != nil {
+			return n, vals
+		}
+	}
+	if the.wildcard != nil && the.wildcard.entries != nil {
+		return the.wildcard, append(values, the.wildcard.paramName, strings.Join(segments, "/"))
+	}
+	return nil, values
+}
+
+func parseParamSegment(segment string) (string, *regexp.Regexp) {
+	segment = segment[1 : len(segment)-1]
+	name := segment
+	var reg *regexp.Regexp
+	if index := strings.Index(segment, ":"); index != -1 {
+		name = segment[:index]
+		reg = regexp.MustCompile("^(?:" + segment[index+1:] + ")$")
+	}
+	return strings.TrimSpace(name), reg
+}
+
+func regString(reg *regexp.Regexp) string {
+	if reg == nil {
+		return ""
+	}
+	return reg.String()
+}
+
+func splitPath(path string) []string {
+	var segments []string
+	for _, segment := range strings.Split(path, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+// 按转义后的路径拆分再逐段还原, 参数里的 %2F 不会被当成分隔符
+func splitEscapedPath(path string) []string {
+	segments := splitPath(path)
+	for i, segment := range segments {
+		if strings.Contains(segment, "%") {
+			if val, err := url.PathUnescape(segment); err == nil {
+				segments[i] = val
+			}
+		}
+	}
+	return segments
+}
+
+func joinPath(prefix string, path string) string {
+	return "/" + strings.Trim(strings.TrimRight(prefix, "/")+"/"+strings.TrimLeft(path, "/"), "/")
+}
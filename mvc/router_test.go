@@ -0,0 +1,132 @@
+package mvc
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func testRouter() *Router {
+	router := NewRouter()
+	router.Get("/users", "User", "List").Name("users")
+	router.Get("/users/new", "User", "New")
+	router.Get("/users/{id:[0-9]+}", "User", "Find").Name("user")
+	router.Get("/users/{name}", "User", "FindByName").Name("user.name")
+	router.Put("/users/{id:[0-9]+}", "User", "Update")
+	router.Get("/static/*filepath", "Static", "File").Name("static")
+	router.Group("/api", func(api *Router) {
+		api.Post("/orders/{id}/items", "Order", "AddItem")
+		api.Any("/ping", "Api", "Ping")
+	})
+	return router
+}
+
+func TestRouterMatch(t *testing.T) {
+	router := testRouter()
+	tests := []struct {
+		method string
+		path   string
+		name   string
+		params map[string]string
+	}{
+		{http.MethodGet, "/users", "List", nil},
+		{http.MethodGet, "/users/", "List", nil},
+		{http.MethodGet, "/users/new", "New", nil},
+		{http.MethodGet, "/users/12", "Find", map[string]string{"id": "12"}},
+		{http.MethodGet, "/users/bob", "FindByName", map[string]string{"name": "bob"}},
+		{http.MethodGet, "/users/a%2Fb", "FindByName", map[string]string{"name": "a/b"}},
+		{http.MethodHead, "/users/12", "Find", map[string]string{"id": "12"}},
+		{http.MethodPut, "/users/12", "Update", map[string]string{"id": "12"}},
+		{http.MethodGet, "/static/css/a.css", "File", map[string]string{"filepath": "css/a.css"}},
+		{http.MethodGet, "/static", "File", map[string]string{"filepath": ""}},
+		{http.MethodPost, "/api/orders/7/items", "AddItem", map[string]string{"id": "7"}},
+		{http.MethodDelete, "/api/ping", "Ping", nil},
+	}
+	for _, test := range tests {
+		route, err := router.Handler(httptest.NewRequest(test.method, test.path, nil))
+		if err != nil {
+			t.Errorf("%s %s: %v", test.method, test.path, err)
+			continue
+		}
+		if route.MethodName != test.name || !reflect.DeepEqual(route.Params, test.params) {
+			t.Errorf("%s %s: %s %v, want %s %v", test.method, test.path, route.MethodName, route.Params, test.name, test.params)
+		}
+	}
+}
+
+func TestRouterNotMatch(t *testing.T) {
+	router := testRouter()
+	if _, err := router.Handler(httptest.NewRequest(http.MethodGet, "/nothing", nil)); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("err = %v, want ErrNotFound", err)
+	}
+	_, err := router.Handler(httptest.NewRequest(http.MethodPost, "/users/12", nil))
+	var methodErr *MethodNotAllowedError
+	if !errors.As(err, &methodErr) {
+		t.Fatalf("err = %v, want MethodNotAllowedError", err)
+	}
+	sort.Strings(methodErr.Allow)
+	if !reflect.DeepEqual(methodErr.Allow, []string{http.MethodGet, http.MethodPut}) {
+		t.Fatalf("allow = %v", methodErr.Allow)
+	}
+}
+
+func TestRouterURL(t *testing.T) {
+	router := testRouter()
+	tests := []struct {
+		name   string
+		params map[string]string
+		url    string
+	}{
+		{"users", nil, "/users"},
+		{"user", map[string]string{"id": "12"}, "/users/12"},
+		{"user.name", map[string]string{"name": "a b?c#d%e/f"}, "/users/a%20b%3Fc%23d%25e%2Ff"},
+		{"static", map[string]string{"filepath": "/css/a b.css"}, "/static/css/a%20b.css"},
+	}
+	for _, test := range tests {
+		url, err := router.URL(test.name, test.params)
+		if err != nil || url != test.url {
+			t.Errorf("%s: %s %v, want %s", test.name, url, err, test.url)
+			continue
+		}
+		//生成的路径能匹配回原来的参数
+		route, err := router.Handler(httptest.NewRequest(http.MethodGet, url, nil))
+		if err != nil || route.Name != test.name {
+			t.Errorf("%s: %s matched %s %v", test.name, url, route.Name, err)
+		}
+	}
+	if _, err := router.URL("user", map[string]string{"id": "abc"}); err == nil {
+		t.Error("want error for id not matching [0-9]+")
+	}
+	if _, err := router.URL("user", nil); err == nil {
+		t.Error("want error for missing id")
+	}
+	if _, err := router.URL("nothing", nil); err == nil {
+		t.Error("want error for unknown name")
+	}
+}
+
+func TestRouterConflict(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("want panic for duplicate route")
+		}
+	}()
+	router := NewRouter()
+	router.Get("/a", "A", "B")
+	router.Get("/a/", "A", "C")
+}
+
+func TestRouterMount(t *testing.T) {
+	router := NewRouter()
+	router.Mount("/files", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(req.Method + " " + req.URL.Path))
+	}))
+	server := &Server{Handler: router.Handler}
+	w := serve(server, httptest.NewRequest(http.MethodPatch, "/files/abc", nil))
+	if w.Body.String() != "PATCH /files/abc" {
+		t.Fatalf("body %s", w.Body.String())
+	}
+}
@@ -0,0 +1,199 @@
+package mvc
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+var (
+	serializers = map[string]Serializer{
+		"application/json": JsonSerializer{},
+		"application/xml":  XmlSerializer{},
+		"text/xml":         XmlSerializer{},
+	}
+	serializersGuard sync.RWMutex
+)
+
+type JsonSerializer struct{}
+
+func (the JsonSerializer) Serialize(w io.Writer, val interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false) //不转译html字符
+	return encoder.Encode(val)
+}
+
+// XmlSerializer 结构按 encoding/xml 的规则输出, map 和切片这样没有根元素的值包在 <response> 里,
+// map 的 key 是元素名, 切片的每个元素是 <item>
+type XmlSerializer struct{}
+
+func (the XmlSerializer) Serialize(w io.Writer, val interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	value := reflect.ValueOf(val)
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			break
+		}
+		value = value.Elem()
+	}
+	if value.Kind() == reflect.Struct || (val != nil && reflect.TypeOf(val).Implements(xmlMarshalerType)) {
+		return encoder.Encode(val)
+	}
+	return encoder.EncodeElement(xmlValue{val: val}, xml.StartElement{Name: xml.Name{Local: "response"}})
+}
+
+var xmlMarshalerType = reflect.TypeOf((*xml.Marshaler)(nil)).Elem()
+
+type xmlValue struct {
+	val interface{}
+}
+
+func (the xmlValue) MarshalXML(encoder *xml.Encoder, start xml.StartElement) error {
+	value := reflect.ValueOf(the.val)
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			value = reflect.Value{}
+			break
+		}
+		value = value.Elem()
+	}
+	switch {
+	case !value.IsValid():
+		return encoder.EncodeElement("", start)
+	case value.Kind() == reflect.Map:
+		if err := encoder.EncodeToken(start); err != nil {
+			return err
+		}
+		keys := value.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, key := range keys {
+			name := xml.Name{Local: xmlName(fmt.Sprint(key.Interface()))}
+			if err := encoder.EncodeElement(xmlValue{val: value.MapIndex(key).Interface()}, xml.StartElement{Name: name}); err != nil {
+				return err
+			}
+		}
+		return encoder.EncodeToken(start.End())
+	case (value.Kind() == reflect.Slice || value.Kind() == reflect.Array) && value.Type().Elem().Kind() != reflect.Uint8:
+		if err := encoder.EncodeToken(start); err != nil {
+			return err
+		}
+		for i := 0; i < value.Len(); i++ {
+			if err := encoder.EncodeElement(xmlValue{val: value.Index(i).Interface()}, xml.StartElement{Name: xml.Name{Local: "item"}}); err != nil {
+				return err
+			}
+		}
+		return encoder.EncodeToken(start.End())
+	}
+	return encoder.EncodeElement(value.Interface(), start)
+}
+
+// map 的 key 当元素名, 不能用在元素名里的字符换成 _, 数字开头的前面加 _
+func xmlName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		if r == '_' || r == '-' || r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return '_'
+	}, key)
+	if name == "" || !(name[0] == '_' || unicode.IsLetter([]rune(name)[0])) {
+		name = "_" + name
+	}
+	return name
+}
+
+// RegisterSerializer 注册序列化方式, 如 application/msgpack
+func RegisterSerializer(contentType string, serializer Serializer) {
+	contentType = strings.ToLower(strings.TrimSpace(contentType))
+	//防止并发写map异常
+	serializersGuard.Lock()
+	serializers[contentType] = serializer
+	serializersGuard.Unlock()
+}
+
+// 按 Accept 的 q 值从高到低找注册过的序列化方式, 都没有就用 Server.DefaultContentType
+func negotiate(server *Server, req *http.Request) (string, Serializer) {
+	defaultType := strings.ToLower(strings.TrimSpace(server.DefaultContentType))
+	if defaultType == "" {
+		defaultType = "application/json"
+	}
+	serializersGuard.RLock()
+	defer serializersGuard.RUnlock()
+	type accept struct {
+		mediaType string
+		q         float64
+	}
+	var accepts []accept
+	for _, item := range strings.Split(req.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(item))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if val, ok := params["q"]; ok {
+			if val, err := strconv.ParseFloat(val, 64); err == nil {
+				q = val
+			}
+		}
+		if q > 0 {
+			accepts = append(accepts, accept{mediaType: mediaType, q: q})
+		}
+	}
+	sort.SliceStable(accepts, func(i, j int) bool {
+		return accepts[i].q > accepts[j].q
+	})
+	for _, item := range accepts {
+		if item.mediaType == "*/*" {
+			break
+		}
+		if serializer, ok := serializers[item.mediaType]; ok {
+			return item.mediaType, serializer
+		}
+		if strings.HasSuffix(item.mediaType, "/*") {
+			prefix := strings.TrimSuffix(item.mediaType, "*")
+			if strings.HasPrefix(defaultType, prefix) {
+				break
+			}
+			var types []string
+			for contentType := range serializers {
+				if strings.HasPrefix(contentType, prefix) {
+					types = append(types, contentType)
+				}
+			}
+			if len(types) > 0 {
+				sort.Strings(types)
+				return types[0], serializers[types[0]]
+			}
+		}
+	}
+	return lookupDefault(defaultType)
+}
+
+// Server.DefaultContentType 对应的序列化方式, 序列化失败时换成它再试一次
+func defaultSerializer(server *Server) (string, Serializer) {
+	defaultType := strings.ToLower(strings.TrimSpace(server.DefaultContentType))
+	serializersGuard.RLock()
+	defer serializersGuard.RUnlock()
+	return lookupDefault(defaultType)
+}
+
+// 调用方持有 serializersGuard, 没有注册就用 json
+func lookupDefault(defaultType string) (string, Serializer) {
+	if serializer, ok := serializers[defaultType]; ok {
+		return defaultType, serializer
+	}
+	return "application/json", JsonSerializer{}
+}
@@ -0,0 +1,109 @@
+package mvc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type serializeItem struct {
+	Name  string
+	Count int
+}
+
+type serializeTags struct {
+	Tags map[string]string
+}
+
+func (the *testController) SerializeMap() map[string]interface{} {
+	return map[string]interface{}{"name": "monster", "count": 2, "1st": true}
+}
+
+func (the *testController) SerializeSlice() []serializeItem {
+	return []serializeItem{{Name: "a", Count: 1}, {Name: "b", Count: 2}}
+}
+
+func (the *testController) SerializeStruct() serializeItem {
+	return serializeItem{Name: "a", Count: 1}
+}
+
+// xml 不支持 map 字段, 要换成默认的序列化方式
+func (the *testController) SerializeTags() serializeTags {
+	return serializeTags{Tags: map[string]string{"a": "b"}}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept      string
+		defaultType string
+		want        string
+	}{
+		{"", "", "application/json"},
+		{"", "application/xml", "application/xml"},
+		{"application/xml", "", "application/xml"},
+		{"text/xml", "", "text/xml"},
+		{"application/json;q=0.5, application/xml", "", "application/xml"},
+		{"application/xml;q=0.5, application/json;q=0.8", "", "application/json"},
+		{"application/xml;q=0, application/json;q=0.1", "", "application/json"},
+		{"*/*", "application/xml", "application/xml"},
+		{"text/html, */*;q=0.8", "", "application/json"},
+		{"application/*", "", "application/json"},
+		{"application/*", "text/xml", "application/json"},
+		{"text/*", "", "text/xml"},
+		{"image/png", "application/xml", "application/xml"},
+		{"application/msgpack", "unknown/type", "application/json"},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if test.accept != "" {
+			req.Header.Set("Accept", test.accept)
+		}
+		contentType, _ := negotiate(&Server{DefaultContentType: test.defaultType}, req)
+		if contentType != test.want {
+			t.Errorf("Accept %q, default %q: got %s, want %s", test.accept, test.defaultType, contentType, test.want)
+		}
+	}
+}
+
+func TestSerializeXml(t *testing.T) {
+	tests := []struct {
+		methodName string
+		want       string
+	}{
+		{"SerializeMap", "<response><_1st>true</_1st><count>2</count><name>monster</name></response>"},
+		{"SerializeSlice", "<response><item><Name>a</Name><Count>1</Count></item><item><Name>b</Name><Count>2</Count></item></response>"},
+		{"SerializeStruct", "<serializeItem><Name>a</Name><Count>1</Count></serializeItem>"},
+	}
+	for _, test := range tests {
+		server := testServer(http.MethodGet, "/serialize", test.methodName)
+		req := httptest.NewRequest(http.MethodGet, "/serialize", nil)
+		req.Header.Set("Accept", "application/xml")
+		w := serve(server, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status %d, body %s", test.methodName, w.Code, w.Body.String())
+		}
+		if contentType := w.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "application/xml") {
+			t.Errorf("%s: content type %s", test.methodName, contentType)
+		}
+		if body := strings.TrimPrefix(w.Body.String(), `<?xml version="1.0" encoding="UTF-8"?>`+"\n"); body != test.want {
+			t.Errorf("%s: got %s, want %s", test.methodName, body, test.want)
+		}
+	}
+}
+
+func TestSerializeFallback(t *testing.T) {
+	server := testServer(http.MethodGet, "/serialize", "SerializeTags")
+	req := httptest.NewRequest(http.MethodGet, "/serialize", nil)
+	req.Header.Set("Accept", "application/xml")
+	w := serve(server, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d, body %s", w.Code, w.Body.String())
+	}
+	if contentType := w.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "application/json") {
+		t.Errorf("content type %s", contentType)
+	}
+	if body := strings.TrimSpace(w.Body.String()); body != `{"Tags":{"a":"b"}}` {
+		t.Errorf("body %s", body)
+	}
+}
@@ -52,7 +52,7 @@ func Serve(servers ...*Server) {
 				routeHandle(server, w, req)
 			}
 		}
-		httpServer := &http.Server{Addr: addr, Handler: handlerFunc(server), ErrorLog: log.New(monster.ErrorLog.Out, "", 0)}
+		httpServer := &http.Server{Addr: addr, Handler: handlerFunc(server), ErrorLog: log.New(monster.LogWriter(monster.ErrorLog), "", 0)}
 		if server.Prepare != nil {
 			server.Prepare(server, httpServer)
 		}
@@ -1,8 +1,10 @@
 package mvc
 
 import (
+	"net"
 	"net/http"
 	"os"
+	"sync"
 )
 
 type Server struct {
@@ -11,7 +13,21 @@ type Server struct {
 	Interceptors []Interceptor
 	CertFile     string
 	KeyFile      string
-	Prepare      func(server *Server, httpServer *http.Server) //允许修改原始 http.Server 信息
+	Prepare      func(server *Server, httpServer *http.Server)             //允许修改原始 http.Server 信息
+	ErrorHandler func(w http.ResponseWriter, req *http.Request, err error) //控制器返回的 error 怎么输出, 默认 DefaultErrorHandler
+	//控制器返回普通值时, Accept 没有匹配的序列化方式就用这个, 默认 application/json
+	DefaultContentType string
+	Templates          *Templates //mvc.HTML 视图用的模板
+	//参数结构里没有来源标签的字段不再按字段名兜底取值, 只认 json 标签的名字
+	ExplicitBinding bool
+	//严格模式: 类型转换失败, json 格式错误, json 多余字段, 数组装不下都输出 400, 不再悄悄用零值
+	StrictBinding bool
+	Decoders      map[string]Decoder //按 Content-Type 解析请求 body, 优先于 RegisterDecoder 注册的, 默认支持 json 和 xml
+	Limits        Limits             //请求大小限制, 路由上可以单独设置
+	//可信的反向代理, ip 或网段, 如 127.0.0.1, 10.0.0.0/8, 直接连接的地址在里面时 Context.ClientIP 才认 X-Forwarded-For
+	TrustedProxies []string
+	trustedOnce    sync.Once
+	trustedNets    []*net.IPNet
 }
 
 type File struct {
@@ -22,4 +38,8 @@ type File struct {
 type Route struct {
 	ControllerName string
 	MethodName     string
+	Name           string            //命名路由的名称
+	Params         map[string]string //路径参数, 如 /users/{id} 里的 id
+	Limits         *Limits           //这条路由的请求大小限制, 覆盖 Server.Limits
+	Handler        http.Handler      //不为空时直接交给它处理, 不再调用控制器, 见 Router.Mount
 }
@@ -0,0 +1,194 @@
+package mvc
+
+import (
+	"bytes"
+	"errors"
+	"github.com/luoshanzhi/monster-go"
+	"html/template"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+// Templates 基于 html/template 的模板引擎, 模板名为去掉扩展名的相对路径, 如 users/index
+// LayoutDir 下是布局, PartialDir 下是公共片段, 都会和每个页面一起解析, 其他文件都是页面
+// 页面用 {{define "content"}} 覆盖布局里的 {{block "content" .}}
+// release 环境只解析一次, 其他环境每次渲染都重新解析, 改模板不用重启
+type Templates struct {
+	FS            fs.FS
+	Ext           string //模板扩展名, 默认 .html
+	LayoutDir     string //默认 layouts
+	PartialDir    string //默认 partials
+	DefaultLayout string //默认布局, 如 layouts/base, 为空时直接执行页面
+	Funcs         template.FuncMap
+	mutex         sync.RWMutex
+	pages         map[string]*template.Template
+}
+
+// NewTemplates 从目录加载模板
+func NewTemplates(dir string) *Templates {
+	return NewTemplatesFS(os.DirFS(dir))
+}
+
+// NewTemplatesFS 从 fs.FS 加载模板, 如 embed.FS
+func NewTemplatesFS(fsys fs.FS) *Templates {
+	return &Templates{
+		FS:         fsys,
+		Ext:        ".html",
+		LayoutDir:  "layouts",
+		PartialDir: "partials",
+	}
+}
+
+// Load 解析全部模板, release 环境第一次渲染时会自动调用, 启动时调用可以提前发现模板错误
+func (the *Templates) Load() error {
+	pages, err := the.parse()
+	if err != nil {
+		return err
+	}
+	the.mutex.Lock()
+	the.pages = pages
+	the.mutex.Unlock()
+	return nil
+}
+
+// Render 渲染页面, layout 为空时用 DefaultLayout
+func (the *Templates) Render(w io.Writer, name string, layout string, data interface{}) error {
+	tpl, err := the.page(name)
+	if err != nil {
+		return err
+	}
+	if layout == "" {
+		layout = the.DefaultLayout
+	}
+	if layout == "" {
+		return tpl.Execute(w, data)
+	}
+	if tpl.Lookup(layout) == nil {
+		return errors.New("template: layout " + layout + " not found")
+	}
+	return tpl.ExecuteTemplate(w, layout, data)
+}
+
+func (the *Templates) page(name string) (*template.Template, error) {
+	var pages map[string]*template.Template
+	if monster.CurEnv == "release" {
+		the.mutex.RLock()
+		pages = the.pages
+		the.mutex.RUnlock()
+		if pages == nil {
+			if err := the.Load(); err != nil {
+				return nil, err
+			}
+			the.mutex.RLock()
+			pages = the.pages
+			the.mutex.RUnlock()
+		}
+	} else {
+		var err error
+		if pages, err = the.parse(); err != nil {
+			return nil, err
+		}
+	}
+	tpl, ok := pages[name]
+	if !ok {
+		return nil, errors.New("template: " + name + " not found")
+	}
+	return tpl, nil
+}
+
+func (the *Templates) parse() (map[string]*template.Template, error) {
+	if the.FS == nil {
+		return nil, errors.New("template: FS is nil")
+	}
+	ext := the.Ext
+	if ext == "" {
+		ext = ".html"
+	}
+	type file struct {
+		name    string
+		content string
+	}
+	var shared, pages []file
+	err := fs.WalkDir(the.FS, ".", func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || path.Ext(filePath) != ext {
+			return nil
+		}
+		content, err := fs.ReadFile(the.FS, filePath)
+		if err != nil {
+			return err
+		}
+		f := file{name: strings.TrimSuffix(filePath, ext), content: string(content)}
+		if inDir(filePath, the.LayoutDir) || inDir(filePath, the.PartialDir) {
+			shared = append(shared, f)
+		} else {
+			pages = append(pages, f)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	//布局和片段只解析一次, 每个页面克隆一份, 页面之间的 define 互不影响
+	base := template.New("").Funcs(the.Funcs)
+	for _, f := range shared {
+		if _, err := base.New(f.name).Parse(f.content); err != nil {
+			return nil, err
+		}
+	}
+	result := make(map[string]*template.Template, len(pages))
+	for _, f := range pages {
+		tpl, err := base.Clone()
+		if err != nil {
+			return nil, err
+		}
+		if tpl, err = tpl.New(f.name).Parse(f.content); err != nil {
+			return nil, err
+		}
+		result[f.name] = tpl
+	}
+	return result, nil
+}
+
+func inDir(filePath string, dir string) bool {
+	dir = strings.Trim(dir, "/")
+	return dir != "" && strings.HasPrefix(filePath, dir+"/")
+}
+
+// HTMLView 用 Server.Templates 渲染页面, 先渲染到缓存, 模板出错时输出 500 而不是半个页面
+type HTMLView struct {
+	Status    int
+	Header    map[string]string
+	Name      string
+	Layout    string //为空时用 Templates.DefaultLayout
+	Data      interface{}
+	Templates *Templates //为空时用 Server.Templates
+}
+
+func HTML(name string, data interface{}) *HTMLView {
+	return &HTMLView{Name: name, Data: data}
+}
+
+func (the *HTMLView) Out(w http.ResponseWriter, req *http.Request) error {
+	templates := the.Templates
+	if templates == nil {
+		if server := serverOf(req); server != nil {
+			templates = server.Templates
+		}
+	}
+	if templates == nil {
+		return errors.New("template: Server.Templates is nil")
+	}
+	buf := new(bytes.Buffer)
+	if err := templates.Render(buf, the.Name, the.Layout, the.Data); err != nil {
+		return err
+	}
+	return bytesOut(w, the.Status, the.Header, "text/html; charset=utf-8", buf.Bytes())
+}
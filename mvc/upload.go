@@ -0,0 +1,228 @@
+package mvc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Storage 上传文件保存的地方, 比如本地磁盘, 对象存储, name 是用 / 分隔的相对路径
+type Storage interface {
+	Save(ctx context.Context, name string, reader io.Reader) error
+	Open(ctx context.Context, name string) (io.ReadCloser, error)
+	Delete(ctx context.Context, name string) error
+}
+
+// UploadOptions 保存上传文件的选项, 类型和大小不符合时返回 400 和 413 错误, 控制器可以直接返回
+type UploadOptions struct {
+	Dir        string   //保存到 Storage 的哪个目录, 如 avatar/2024
+	MaxSize    int64    //最大字节数, 0 不限制
+	AllowTypes []string //允许的类型, 按文件内容判断, 如 image/png, 为空不限制
+	RandomName bool     //用随机文件名, 只保留扩展名, 不会覆盖已有文件
+}
+
+// UploadFile 保存好的上传文件
+type UploadFile struct {
+	Name        string //清理后的原始文件名
+	Path        string //Storage 里的路径
+	Size        int64
+	ContentType string //按文件内容判断的类型
+	Hash        string //sha256, 十六进制
+}
+
+// SaveUpload 检查并保存 parseParam 取到的上传文件, 内容一边写入 Storage 一边计算 sha256
+func SaveUpload(ctx context.Context, storage Storage, fileHeader *multipart.FileHeader, options UploadOptions) (*UploadFile, error) {
+	if fileHeader == nil {
+		return nil, BadRequest("上传文件不能为空")
+	}
+	name := SanitizeFileName(fileHeader.Filename)
+	if options.MaxSize > 0 && fileHeader.Size > options.MaxSize {
+		return nil, TooLarge("上传文件太大: " + name)
+	}
+	contentType, err := SniffContentType(fileHeader)
+	if err != nil {
+		return nil, BadRequest("上传文件读取失败", err)
+	}
+	if len(options.AllowTypes) > 0 && !inStrings(options.AllowTypes, contentType) {
+		return nil, BadRequest("上传文件类型错误: " + contentType)
+	}
+	saveName := name
+	if options.RandomName {
+		saveName = RandomFileName(name)
+	}
+	//Dir 里的 ../ 不能跳出 Storage
+	savePath := strings.TrimPrefix(path.Clean("/"+path.Join(filepath.ToSlash(options.Dir), saveName)), "/")
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, BadRequest("上传文件读取失败", err)
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if err := storage.Save(ctx, savePath, io.TeeReader(file, hash)); err != nil {
+		return nil, err
+	}
+	return &UploadFile{
+		Name:        name,
+		Path:        savePath,
+		Size:        fileHeader.Size,
+		ContentType: contentType,
+		Hash:        hex.EncodeToString(hash.Sum(nil)),
+	}, nil
+}
+
+// SniffContentType 按文件前 512 字节判断类型, 不信任客户端传的 Content-Type
+func SniffContentType(fileHeader *multipart.FileHeader) (string, error) {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(file, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	contentType := http.DetectContentType(buf[:n])
+	if i := strings.Index(contentType, ";"); i != -1 {
+		contentType = contentType[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(contentType)), nil
+}
+
+// SanitizeFileName 去掉路径和不能用在文件名里的字符, 如 ../../a.php => a.php, 空名字返回 file
+func SanitizeFileName(name string) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+	name = path.Base(name)
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || strings.ContainsRune(`<>:"/\|?*`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	name = strings.TrimLeft(strings.TrimSpace(name), ".")
+	if name == "" {
+		return "file"
+	}
+	//大部分文件系统限制 255 字节, 截断时保留扩展名
+	if len(name) > 255 {
+		ext := path.Ext(name)
+		if len(ext) > 32 {
+			ext = ""
+		}
+		base := name[:255-len(ext)]
+		for !utf8.ValidString(base) {
+			base = base[:len(base)-1]
+		}
+		name = base + ext
+	}
+	return name
+}
+
+// RandomFileName 随机文件名, 保留小写扩展名
+func RandomFileName(name string) string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	ext := strings.ToLower(path.Ext(SanitizeFileName(name)))
+	for _, r := range strings.TrimPrefix(ext, ".") {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9') {
+			ext = ""
+			break
+		}
+	}
+	return hex.EncodeToString(buf) + ext
+}
+
+// LocalStorage 保存到本地目录
+type LocalStorage struct {
+	Root string
+}
+
+func NewLocalStorage(root string) *LocalStorage {
+	return &LocalStorage{Root: root}
+}
+
+// 不允许 ../ 跳出 Root, 不允许控制字符
+func (the *LocalStorage) path(name string) (string, error) {
+	if strings.IndexFunc(name, unicode.IsControl) != -1 {
+		return "", errors.New("storage: invalid name")
+	}
+	name = path.Clean("/" + strings.ReplaceAll(name, "\\", "/"))
+	if name == "/" {
+		return "", errors.New("storage: empty name")
+	}
+	return filepath.Join(the.Root, filepath.FromSlash(name)), nil
+}
+
+// Save 先写临时文件再改名, 写到一半失败不会留下不完整的文件
+func (the *LocalStorage) Save(ctx context.Context, name string, reader io.Reader) error {
+	filePath, err := the.path(name)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(filePath)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, ctxReader{ctx: ctx, reader: reader}); err != nil {
+		tmp.Close()
+		return err
+	}
+	//os.CreateTemp 创建的文件只有自己可读
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filePath)
+}
+
+func (the *LocalStorage) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	filePath, err := the.path(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(filePath)
+}
+
+func (the *LocalStorage) Delete(ctx context.Context, name string) error {
+	filePath, err := the.path(name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(filePath)
+}
+
+// 复制过程中 ctx 取消(比如客户端断开)就停止
+type ctxReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (the ctxReader) Read(p []byte) (int, error) {
+	if the.ctx != nil {
+		if err := the.ctx.Err(); err != nil {
+			return 0, err
+		}
+	}
+	return the.reader.Read(p)
+}
@@ -0,0 +1,94 @@
+package mvc
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeFileName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"a.png", "a.png"},
+		{"../../a.php", "a.php"},
+		{"/etc/passwd", "passwd"},
+		{`..\..\windows\a.exe`, "a.exe"},
+		{`C:\Users\a.txt`, "a.txt"},
+		{"a\x00b\nc.txt", "a_b_c.txt"},
+		{`a<b>c:d"e|f?g*h.txt`, "a_b_c_d_e_f_g_h.txt"},
+		{"..", "file"},
+		{"../", "file"},
+		{".htaccess", "htaccess"},
+		{"  头像.jpg  ", "头像.jpg"},
+		{"", "file"},
+		{strings.Repeat("a", 300) + ".png", strings.Repeat("a", 251) + ".png"},
+		{strings.Repeat("图", 100) + ".png", strings.Repeat("图", 83) + ".png"},
+	}
+	for _, test := range tests {
+		if got := SanitizeFileName(test.name); got != test.want {
+			t.Errorf("%q: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestRandomFileName(t *testing.T) {
+	tests := []struct {
+		name string
+		ext  string
+	}{
+		{"a.PNG", ".png"},
+		{"../../a.php", ".php"},
+		{`..\a.jpg`, ".jpg"},
+		{"a.p/hp", ""},
+		{"a.ph\x00p", ""},
+		{"a.图", ""},
+		{"noext", ""},
+	}
+	nameReg := regexp.MustCompile(`^[0-9a-f]{32}(\.[a-z0-9]+)?$`)
+	for _, test := range tests {
+		got := RandomFileName(test.name)
+		if !nameReg.MatchString(got) || !strings.HasSuffix(got, test.ext) || (test.ext == "" && strings.Contains(got, ".")) {
+			t.Errorf("%q: got %q, want ext %q", test.name, got, test.ext)
+		}
+	}
+	if RandomFileName("a.png") == RandomFileName("a.png") {
+		t.Errorf("random names repeated")
+	}
+}
+
+func TestLocalStoragePath(t *testing.T) {
+	root := filepath.FromSlash("/data/upload")
+	storage := NewLocalStorage(root)
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"a.png", "a.png"},
+		{"avatar/2024/a.png", "avatar/2024/a.png"},
+		{"../a.png", "a.png"},
+		{"../../etc/passwd", "etc/passwd"},
+		{"avatar/../../a.png", "a.png"},
+		{`..\..\a.png`, "a.png"},
+		{`avatar\a.png`, "avatar/a.png"},
+		{"/abs/a.png", "abs/a.png"},
+		{"", ""},
+		{"..", ""},
+		{"a\x00.png", ""},
+		{"a\n.png", ""},
+	}
+	for _, test := range tests {
+		got, err := storage.path(test.name)
+		if test.want == "" {
+			if err == nil {
+				t.Errorf("%q: got %q, want error", test.name, got)
+			}
+			continue
+		}
+		if want := filepath.Join(root, filepath.FromSlash(test.want)); err != nil || got != want {
+			t.Errorf("%q: got %q %v, want %q", test.name, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,382 @@
+package mvc
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// 参数校验, 写在参数结构的 validate 标签里, 多个规则用逗号分隔:
+// required           不能为空
+// min=1,max=64       数字比较大小, 字符串比较字符数, 切片比较个数
+// len=11             字符串字符数或切片个数必须等于
+// email              邮箱格式
+// oneof=a b c        只能是其中之一, 用空格分隔
+// regexp=^[0-9]+$    正则, 必须写在最后, 后面的逗号也算正则的一部分
+// maxsize=2MB        上传文件大小, 单位 B, KB, MB, GB
+// mime=image/png|image/jpeg 上传文件类型, 按文件内容判断, 不信任客户端的 Content-Type
+// omitempty          值是 0 时不做其他校验
+// 没有 required 时空字符串, 空切片, nil 不做其他校验; 数字 0 也要校验, 比如 min=1 时 0 不通过, 可以用 omitempty 跳过
+
+// FieldError 单个字段的错误
+type FieldError struct {
+	Field string `json:"field"`
+	Rule  string `json:"rule"`
+	Msg   string `json:"msg"`
+}
+
+// ValidationError 参数错误, 输出 400 和每个字段的错误
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (the *ValidationError) Error() string {
+	var list []string
+	for _, item := range the.Errors {
+		list = append(list, item.Field+item.Msg)
+	}
+	return "参数错误: " + strings.Join(list, "; ")
+}
+
+var errOverflow = errors.New("array overflow")
+
+// 严格模式下取值出错对应的字段错误
+func bindFieldError(field *paramField, err error) FieldError {
+	if errors.Is(err, errOverflow) {
+		valueType := field.typ
+		if valueType.Kind() == reflect.Ptr {
+			valueType = valueType.Elem()
+		}
+		return FieldError{Field: field.label, Rule: "overflow", Msg: "最多 " + strconv.Itoa(valueType.Len()) + " 个"}
+	}
+	return FieldError{Field: field.label, Rule: "type", Msg: "类型错误"}
+}
+
+// 请求 body 解析出错对应的字段错误
+func decodeFieldError(err error) FieldError {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return FieldError{Field: typeErr.Field, Rule: "type", Msg: "类型错误"}
+	}
+	//encoding/json 没有导出这个错误类型: json: unknown field "name"
+	if msg := err.Error(); strings.HasPrefix(msg, "json: unknown field ") {
+		name, _ := strconv.Unquote(strings.TrimPrefix(msg, "json: unknown field "))
+		return FieldError{Field: name, Rule: "unknown", Msg: "不支持的字段"}
+	}
+	var syntaxErr *json.SyntaxError
+	var xmlErr *xml.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return FieldError{Field: "body", Rule: "json", Msg: "json 格式错误"}
+	} else if errors.As(err, &xmlErr) {
+		return FieldError{Field: "body", Rule: "xml", Msg: "xml 格式错误"}
+	}
+	return FieldError{Field: "body", Rule: "decode", Msg: "请求内容格式错误"}
+}
+
+type rule struct {
+	name  string
+	param string
+	num   float64
+	reg   *regexp.Regexp
+	list  []string
+}
+
+func parseRules(tag string) ([]rule, error) {
+	var rules []rule
+	for tag = strings.TrimSpace(tag); tag != ""; {
+		var item string
+		if strings.HasPrefix(tag, "regexp=") {
+			item, tag = tag, ""
+		} else if i := strings.Index(tag, ","); i != -1 {
+			item, tag = tag[:i], strings.TrimSpace(tag[i+1:])
+		} else {
+			item, tag = tag, ""
+		}
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		r := rule{name: item}
+		if i := strings.Index(item, "="); i != -1 {
+			r.name, r.param = item[:i], item[i+1:]
+		}
+		var err error
+		switch r.name {
+		case "required", "omitempty", "email":
+		case "min", "max", "len":
+			r.num, err = strconv.ParseFloat(r.param, 64)
+		case "oneof":
+			r.list = strings.Fields(r.param)
+		case "regexp":
+			r.reg, err = regexp.Compile(r.param)
+		case "maxsize":
+			var size int64
+			size, err = parseSize(r.param)
+			r.num = float64(size)
+		case "mime":
+			for _, item := range strings.Split(r.param, "|") {
+				if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+					r.list = append(r.list, item)
+				}
+			}
+		default:
+			err = errors.New("unknown rule")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("validate: %s: %w", item, err)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// 解析 2MB, 512KB, 100 这样的大小
+func parseSize(str string) (int64, error) {
+	str = strings.ToUpper(strings.TrimSpace(str))
+	unit := int64(1)
+	for _, item := range []struct {
+		suffix string
+		unit   int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(str, item.suffix) {
+			str, unit = strings.TrimSpace(strings.TrimSuffix(str, item.suffix)), item.unit
+			break
+		}
+	}
+	size, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return 0, err
+	}
+	return int64(size * float64(unit)), nil
+}
+
+func validateParam(plan *paramPlan, objValue reflect.Value) error {
+	var fieldErrors []FieldError
+	if err := validateStruct(plan, objValue, "", &fieldErrors); err != nil {
+		return err
+	}
+	if len(fieldErrors) > 0 {
+		return &ValidationError{Errors: fieldErrors}
+	}
+	return nil
+}
+
+// 校验结构的每个字段, 嵌套的结构, 结构切片, map 里的结构也校验, 字段名加上路径, 如 items.0.id
+func validateStruct(plan *paramPlan, objValue reflect.Value, prefix string, fieldErrors *[]FieldError) error {
+	if plan.err != nil {
+		return plan.err
+	}
+	for i := range plan.fields {
+		field := &plan.fields[i]
+		label := field.label
+		if prefix != "" {
+			label = prefix + "." + label
+		}
+		value, ok := fieldByIndex(objValue, field.index)
+		if !ok {
+			continue
+		}
+		if len(field.rules) > 0 {
+			if msg, ruleName := validateField(field, value); msg != "" {
+				*fieldErrors = append(*fieldErrors, FieldError{Field: label, Rule: ruleName, Msg: msg})
+				continue
+			}
+		}
+		if field.nested {
+			if err := validateNested(value, label, fieldErrors); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateNested(value reflect.Value, label string, fieldErrors *[]FieldError) error {
+	if !isNested(value.Type()) {
+		return nil
+	}
+	if value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+	switch value.Kind() {
+	case reflect.Struct:
+		return validateStruct(getParamPlan(value.Type()), value, label, fieldErrors)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if err := validateNested(value.Index(i), label+"."+strconv.Itoa(i), fieldErrors); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		iter := value.MapRange()
+		for iter.Next() {
+			if err := validateNested(iter.Value(), label+"."+fmt.Sprint(iter.Key().Interface()), fieldErrors); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// 内嵌的匿名指针是 nil 时没有这个字段
+func fieldByIndex(value reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				return reflect.Value{}, false
+			}
+			value = value.Elem()
+		}
+		value = value.Field(x)
+	}
+	return value, true
+}
+
+// 返回第一个不通过的规则
+func validateField(field *paramField, value reflect.Value) (string, string) {
+	if value.IsZero() || (value.Kind() == reflect.Slice && value.Len() == 0) {
+		if hasRule(field.rules, "required") {
+			return "不能为空", "required"
+		}
+		if !isNumber(value.Kind()) || hasRule(field.rules, "omitempty") {
+			return "", ""
+		}
+	}
+	if value.Kind() == reflect.Ptr && value.Type().Elem().Kind() != reflect.Struct {
+		value = value.Elem()
+	}
+	for _, r := range field.rules {
+		if msg := checkRule(r, value); msg != "" {
+			return msg, r.name
+		}
+	}
+	return "", ""
+}
+
+func isNumber(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func hasRule(rules []rule, name string) bool {
+	for _, r := range rules {
+		if r.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func checkRule(r rule, value reflect.Value) string {
+	switch r.name {
+	case "min", "max", "len":
+		num, isLen := measure(value)
+		if r.name == "min" && num < r.num {
+			return lenPrefix(isLen) + "不能小于 " + r.param
+		} else if r.name == "max" && num > r.num {
+			return lenPrefix(isLen) + "不能大于 " + r.param
+		} else if r.name == "len" && num != r.num {
+			return lenPrefix(isLen) + "必须是 " + r.param
+		}
+	case "email":
+		str := fmt.Sprint(value.Interface())
+		if addr, err := mail.ParseAddress(str); err != nil || addr.Address != str {
+			return "邮箱格式错误"
+		}
+	case "oneof":
+		str := fmt.Sprint(value.Interface())
+		if !inStrings(r.list, str) {
+			return "只能是 " + strings.Join(r.list, ", ") + " 其中之一"
+		}
+	case "regexp":
+		if !r.reg.MatchString(fmt.Sprint(value.Interface())) {
+			return "格式错误"
+		}
+	case "maxsize", "mime":
+		for _, fileHeader := range fileHeaders(value) {
+			if msg := checkFile(r, fileHeader); msg != "" {
+				return msg
+			}
+		}
+	}
+	return ""
+}
+
+// 数字取值, 字符串取字符数, 切片和 map 取个数
+func measure(value reflect.Value) (float64, bool) {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), false
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), false
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), false
+	case reflect.String:
+		return float64(utf8.RuneCountInString(value.String())), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(value.Len()), true
+	}
+	return 0, false
+}
+
+func lenPrefix(isLen bool) string {
+	if isLen {
+		return "长度"
+	}
+	return ""
+}
+
+func fileHeaders(value reflect.Value) []*multipart.FileHeader {
+	switch obj := value.Interface().(type) {
+	case *multipart.FileHeader:
+		return []*multipart.FileHeader{obj}
+	case []*multipart.FileHeader:
+		return obj
+	}
+	if value.Kind() == reflect.Array {
+		var list []*multipart.FileHeader
+		for i := 0; i < value.Len(); i++ {
+			if fileHeader, ok := value.Index(i).Interface().(*multipart.FileHeader); ok && fileHeader != nil {
+				list = append(list, fileHeader)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+func checkFile(r rule, fileHeader *multipart.FileHeader) string {
+	if fileHeader == nil {
+		return ""
+	}
+	if r.name == "maxsize" {
+		if float64(fileHeader.Size) > r.num {
+			return "文件不能超过 " + r.param
+		}
+		return ""
+	}
+	contentType, err := SniffContentType(fileHeader)
+	if err != nil {
+		return "文件读取失败"
+	}
+	if !inStrings(r.list, contentType) {
+		return "文件类型只能是 " + strings.Join(r.list, ", ")
+	}
+	return ""
+}
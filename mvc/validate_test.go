@@ -0,0 +1,136 @@
+package mvc
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+)
+
+type validateInner struct {
+	Code string `validate:"required,len=3"`
+}
+
+type checkedParam struct {
+	Name   string                    `validate:"required,min=2,max=4"`
+	Age    int                       `validate:"omitempty,min=1,max=120"`
+	Score  int                       `validate:"min=1,max=100"`
+	Email  string                    `validate:"email"`
+	Kind   string                    `validate:"oneof=a b"`
+	Phone  string                    `validate:"regexp=^1[0-9]{10}$"`
+	Tags   []string                  `validate:"max=2"`
+	Inner  validateInner             `json:"inner"`
+	Items  []validateInner           `json:"items"`
+	Attrs  map[string]*validateInner `json:"attrs"`
+	Avatar *multipart.FileHeader     `validate:"maxsize=1KB,mime=image/png"`
+}
+
+var validateCalled int
+
+func (the *testController) Validate(param checkedParam) *JsonView {
+	validateCalled++
+	return JSON(param.Name)
+}
+
+// 返回状态码和出错的字段, 按字段名排序
+func validateErrors(w *httptest.ResponseRecorder) (int, string) {
+	var data struct {
+		Errors []FieldError `json:"errors"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &data)
+	var fields []string
+	for _, item := range data.Errors {
+		fields = append(fields, item.Field+":"+item.Rule)
+	}
+	sort.Strings(fields)
+	return w.Code, strings.Join(fields, ",")
+}
+
+func TestValidate(t *testing.T) {
+	server := testServer(http.MethodGet, "/validate", "Validate")
+	valid := "name=abc&score=1&inner[code]=xyz"
+	tests := []struct {
+		query  string
+		status int
+		fields string
+	}{
+		{valid, http.StatusOK, ""},
+		{valid + "&age=30&email=a@b.com&kind=b&phone=13800000000&tags=x&tags=y", http.StatusOK, ""},
+		{valid + "&age=0", http.StatusOK, ""},
+		{"name=abc&inner[code]=xyz", http.StatusBadRequest, "score:min"},
+		{"name=abc&score=0&inner[code]=xyz", http.StatusBadRequest, "score:min"},
+		{"score=1&inner[code]=xyz", http.StatusBadRequest, "name:required"},
+		{"name=a&score=1&inner[code]=xyz", http.StatusBadRequest, "name:min"},
+		{"name=abcde&score=1&inner[code]=xyz", http.StatusBadRequest, "name:max"},
+		{valid + "&age=200&email=x&kind=c&phone=123&tags=1&tags=2&tags=3", http.StatusBadRequest, "age:max,email:email,kind:oneof,phone:regexp,tags:max"},
+		{"name=abc&score=1&inner[code]=x", http.StatusBadRequest, "inner.code:len"},
+		{"name=abc&score=1", http.StatusBadRequest, "inner.code:required"},
+		{valid + "&items[0][code]=abc&items[1][code]=a", http.StatusBadRequest, "items.1.code:len"},
+		{valid + "&attrs[k][code]=a", http.StatusBadRequest, "attrs.k.code:len"},
+	}
+	for _, test := range tests {
+		validateCalled = 0
+		w := serve(server, httptest.NewRequest(http.MethodGet, "/validate?"+test.query, nil))
+		status, fields := validateErrors(w)
+		if status != test.status || fields != test.fields {
+			t.Errorf("%s: status %d, fields %s, want %d %s", test.query, status, fields, test.status, test.fields)
+		}
+		//校验不通过不调用控制器
+		if (status == http.StatusOK) != (validateCalled == 1) {
+			t.Errorf("%s: controller called %d times", test.query, validateCalled)
+		}
+	}
+}
+
+// json 提交的嵌套结构也要校验
+func TestValidateJson(t *testing.T) {
+	server := testServer(http.MethodPost, "/validate", "Validate")
+	tests := []struct {
+		body   string
+		status int
+		fields string
+	}{
+		{`{"Name":"abc","Score":1,"inner":{"Code":"xyz"},"items":[{"Code":"toolong"}]}`, http.StatusBadRequest, "items.0.code:len"},
+		//数字 0 也要校验, 有 omitempty 的不校验
+		{`{"Name":"abc","Age":0,"Score":0,"inner":{"Code":"xyz"}}`, http.StatusBadRequest, "score:min"},
+		{`{"Name":"abc","Age":0,"Score":1,"inner":{"Code":"xyz"}}`, http.StatusOK, ""},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/validate", strings.NewReader(test.body))
+		req.Header.Set("Content-Type", "application/json")
+		if status, fields := validateErrors(serve(server, req)); status != test.status || fields != test.fields {
+			t.Errorf("%s: status %d, fields %s", test.body, status, fields)
+		}
+	}
+}
+
+func TestValidateFile(t *testing.T) {
+	server := testServer(http.MethodPost, "/validate", "Validate")
+	upload := func(content []byte) (int, string) {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		writer.WriteField("name", "abc")
+		writer.WriteField("score", "1")
+		writer.WriteField("inner[code]", "xyz")
+		part, _ := writer.CreateFormFile("avatar", "a.png")
+		part.Write(content)
+		writer.Close()
+		req := httptest.NewRequest(http.MethodPost, "/validate", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		return validateErrors(serve(server, req))
+	}
+	png := []byte("\x89PNG\r\n\x1a\n")
+	if status, fields := upload(png); status != http.StatusOK {
+		t.Fatalf("png: status %d, fields %s", status, fields)
+	}
+	if status, fields := upload([]byte("plain text")); status != http.StatusBadRequest || fields != "avatar:mime" {
+		t.Fatalf("text: status %d, fields %s", status, fields)
+	}
+	if status, fields := upload(append(png, make([]byte, 2048)...)); status != http.StatusBadRequest || fields != "avatar:maxsize" {
+		t.Fatalf("large: status %d, fields %s", status, fields)
+	}
+}
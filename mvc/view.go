@@ -0,0 +1,296 @@
+package mvc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// 常用视图, 控制器和拦截器都可以直接返回
+
+var jsonpCallbackReg = regexp.MustCompile(`^[a-zA-Z_$][a-zA-Z0-9_$.]*$`)
+
+type JsonView struct {
+	Status   int
+	Header   map[string]string
+	Data     interface{}
+	Envelope bool //是否包一层 {"code":Code,"msg":Msg,"data":Data}
+	Code     int
+	Msg      string
+}
+
+func JSON(data interface{}) *JsonView {
+	return &JsonView{Data: data}
+}
+
+// JSONEnvelope 输出 {"code":code,"msg":msg,"data":data}
+func JSONEnvelope(code int, msg string, data interface{}) *JsonView {
+	return &JsonView{Data: data, Envelope: true, Code: code, Msg: msg}
+}
+
+func (the *JsonView) Out(w http.ResponseWriter, req *http.Request) error {
+	var data interface{} = the.Data
+	if the.Envelope {
+		data = map[string]interface{}{
+			"code": the.Code,
+			"msg":  the.Msg,
+			"data": the.Data,
+		}
+	}
+	buf := new(bytes.Buffer)
+	if err := (JsonSerializer{}).Serialize(buf, data); err != nil {
+		return err
+	}
+	return bytesOut(w, the.Status, the.Header, "application/json; charset=utf-8", buf.Bytes())
+}
+
+type JsonpView struct {
+	Status   int
+	Header   map[string]string
+	Callback string //为空时取请求参数 callback
+	Data     interface{}
+}
+
+func JSONP(callback string, data interface{}) *JsonpView {
+	return &JsonpView{Callback: callback, Data: data}
+}
+
+func (the *JsonpView) Out(w http.ResponseWriter, req *http.Request) error {
+	callback := strings.TrimSpace(the.Callback)
+	if callback == "" {
+		callback = strings.TrimSpace(req.URL.Query().Get("callback"))
+	}
+	//防止 callback 注入脚本
+	if !jsonpCallbackReg.MatchString(callback) {
+		return errors.New("jsonp callback error")
+	}
+	content, err := json.Marshal(the.Data)
+	if err != nil {
+		return err
+	}
+	buf := new(bytes.Buffer)
+	buf.WriteString("/**/" + callback + "(")
+	buf.Write(content)
+	buf.WriteString(");")
+	return bytesOut(w, the.Status, the.Header, "application/javascript; charset=utf-8", buf.Bytes())
+}
+
+type XmlView struct {
+	Status int
+	Header map[string]string
+	Data   interface{}
+}
+
+func XML(data interface{}) *XmlView {
+	return &XmlView{Data: data}
+}
+
+func (the *XmlView) Out(w http.ResponseWriter, req *http.Request) error {
+	buf := new(bytes.Buffer)
+	if err := (XmlSerializer{}).Serialize(buf, the.Data); err != nil {
+		return err
+	}
+	return bytesOut(w, the.Status, the.Header, "application/xml; charset=utf-8", buf.Bytes())
+}
+
+type TextView struct {
+	Status int
+	Header map[string]string
+	Text   string
+}
+
+func Text(text string) *TextView {
+	return &TextView{Text: text}
+}
+
+func (the *TextView) Out(w http.ResponseWriter, req *http.Request) error {
+	return bytesOut(w, the.Status, the.Header, "text/plain; charset=utf-8", []byte(the.Text))
+}
+
+// TemplateView 用 html/template 渲染, 先渲染到缓存, 模板出错时不会输出半个页面
+type TemplateView struct {
+	Status   int
+	Header   map[string]string
+	Template *template.Template
+	Name     string //为空时执行 Template 本身
+	Data     interface{}
+}
+
+func Template(tpl *template.Template, name string, data interface{}) *TemplateView {
+	return &TemplateView{Template: tpl, Name: name, Data: data}
+}
+
+func (the *TemplateView) Out(w http.ResponseWriter, req *http.Request) error {
+	if the.Template == nil {
+		return errors.New("template is nil")
+	}
+	buf := new(bytes.Buffer)
+	var err error
+	if the.Name == "" {
+		err = the.Template.Execute(buf, the.Data)
+	} else {
+		err = the.Template.ExecuteTemplate(buf, the.Name, the.Data)
+	}
+	if err != nil {
+		return err
+	}
+	return bytesOut(w, the.Status, the.Header, "text/html; charset=utf-8", buf.Bytes())
+}
+
+type RedirectView struct {
+	Status int //默认 302
+	URL    string
+}
+
+func Redirect(url string, status ...int) *RedirectView {
+	view := &RedirectView{URL: url}
+	if len(status) > 0 {
+		view.Status = status[0]
+	}
+	return view
+}
+
+func (the *RedirectView) Out(w http.ResponseWriter, req *http.Request) error {
+	status := the.Status
+	if status == 0 {
+		status = http.StatusFound
+	}
+	if status < 300 || status > 308 {
+		return errors.New("redirect status error")
+	}
+	http.Redirect(w, req, the.URL, status)
+	return nil
+}
+
+// FileView 输出文件, 支持断点续传(Range), Name 不为空时作为下载文件名
+type FileView struct {
+	Header map[string]string
+	Path   string
+	Name   string
+	Inline bool //true 浏览器直接打开, false 下载
+}
+
+// Download 下载文件, name 为空时用文件本身的名字
+func Download(path string, name string) *FileView {
+	return &FileView{Path: path, Name: name}
+}
+
+func (the *FileView) Out(w http.ResponseWriter, req *http.Request) error {
+	file, err := os.Open(the.Path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return errors.New(the.Path + " is dir")
+	}
+	name := the.Name
+	if name == "" {
+		name = filepath.Base(the.Path)
+	}
+	setHeader(w, the.Header)
+	w.Header().Set("Content-Disposition", contentDisposition(name, the.Inline))
+	http.ServeContent(w, req, name, info.ModTime(), file)
+	return nil
+}
+
+type BytesView struct {
+	Status      int
+	Header      map[string]string
+	ContentType string //默认 application/octet-stream
+	Data        []byte
+}
+
+func Bytes(contentType string, data []byte) *BytesView {
+	return &BytesView{ContentType: contentType, Data: data}
+}
+
+func (the *BytesView) Out(w http.ResponseWriter, req *http.Request) error {
+	contentType := the.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return bytesOut(w, the.Status, the.Header, contentType, the.Data)
+}
+
+// StreamView 边读边输出, Reader 实现了 io.Closer 会自动关闭, Name 不为空时作为下载文件名
+type StreamView struct {
+	Status      int
+	Header      map[string]string
+	ContentType string //默认 application/octet-stream
+	Reader      io.Reader
+	Name        string
+}
+
+func Stream(contentType string, reader io.Reader) *StreamView {
+	return &StreamView{ContentType: contentType, Reader: reader}
+}
+
+func (the *StreamView) Out(w http.ResponseWriter, req *http.Request) error {
+	if the.Reader == nil {
+		return errors.New("reader is nil")
+	}
+	if closer, ok := the.Reader.(io.Closer); ok {
+		defer closer.Close()
+	}
+	contentType := the.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	setHeader(w, the.Header)
+	w.Header().Set("Content-Type", contentType)
+	if the.Name != "" {
+		w.Header().Set("Content-Disposition", contentDisposition(the.Name, false))
+	}
+	status := the.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	//已经输出了状态码, 复制出错(一般是客户端断开)也没办法再输出错误
+	io.Copy(w, the.Reader)
+	return nil
+}
+
+func bytesOut(w http.ResponseWriter, status int, header map[string]string, contentType string, content []byte) error {
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.Header().Set("Content-Type", contentType)
+	setHeader(w, header)
+	//必须让 w.WriteHeader 在所有的 w.Header 之后，因为 w.WriteHeader 后 Set Header 是无效的
+	w.WriteHeader(status)
+	_, err := w.Write(content)
+	return err
+}
+
+func setHeader(w http.ResponseWriter, header map[string]string) {
+	for k, val := range header {
+		w.Header().Set(k, val)
+	}
+}
+
+// 文件名带中文等字符时按 RFC 6266 编码
+func contentDisposition(name string, inline bool) string {
+	disposition := "attachment"
+	if inline {
+		disposition = "inline"
+	}
+	if value := mime.FormatMediaType(disposition, map[string]string{"filename": name}); value != "" {
+		return value
+	}
+	return disposition + "; filename*=UTF-8''" + url.PathEscape(name)
+}
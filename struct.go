@@ -10,9 +10,19 @@ type Setting struct {
 
 type EnvConfig struct {
 	LogLevel string
-	Sql      map[string]SqlSetting
-	Redis    map[string][]RedisSettingItem
-	Mongodb  map[string]MongodbSetting
+	LogMode  string //file(默认),stdout(容器模式,不写文件)
+	//运行时修改日志级别: 写入级别到该文件(或 kill -USR1 pid 循环切换), 文件内容为空就恢复
+	LogLevelFile   string
+	LogLevelRevert int                           //运行时修改的日志级别多少秒后自动恢复, <=0 不恢复
+	LogSampling    map[string]LogSamplingSetting //日志去重和限流, key: statistics,access,common,error
+	Sql            map[string]SqlSetting
+	Redis          map[string][]RedisSettingItem
+	Mongodb        map[string]MongodbSetting
+}
+
+type LogSamplingSetting struct {
+	Window    int //相同日志多少秒内只输出一次, 到期输出重复次数, <=0 不去重
+	PerSecond int //每个日志每秒最多输出多少条, <=0 不限制
 }
 
 type SqlSetting struct {